@@ -0,0 +1,152 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
+
+    "github.com/fatih/color"
+)
+
+const checkpointFlushInterval = 2 * time.Second
+
+type checkpointEntry struct {
+    Target string `json:"target"`
+    Result Result `json:"result"`
+}
+
+type checkpoint struct {
+    mu        sync.Mutex
+    file      *os.File
+    writer    *bufio.Writer
+    done      map[string]bool
+    lastFlush time.Time
+}
+
+func openCheckpoint(filename string, resume bool) (*checkpoint, []Result, error) {
+    cp := &checkpoint{done: make(map[string]bool)}
+
+    var previous []Result
+    if resume {
+        var err error
+        previous, err = cp.load(filename)
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+
+    flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+    if resume {
+        flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+    }
+
+    file, err := os.OpenFile(filename, flags, 0644)
+    if err != nil {
+        return nil, nil, fmt.Errorf("opening checkpoint file: %v", err)
+    }
+
+    cp.file = file
+    cp.writer = bufio.NewWriter(file)
+    cp.lastFlush = time.Now()
+    return cp, previous, nil
+}
+
+func (cp *checkpoint) load(filename string) ([]Result, error) {
+    file, err := os.Open(filename)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("reading checkpoint file: %v", err)
+    }
+    defer file.Close()
+
+    var previous []Result
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), maxInputLine)
+    for scanner.Scan() {
+        var entry checkpointEntry
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Target == "" {
+            continue
+        }
+        if cp.done[entry.Target] {
+            continue
+        }
+        cp.done[entry.Target] = true
+        previous = append(previous, entry.Result)
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("reading checkpoint file: %v", err)
+    }
+    return previous, nil
+}
+
+func (cp *checkpoint) completed(target string) bool {
+    if cp == nil {
+        return false
+    }
+
+    cp.mu.Lock()
+    defer cp.mu.Unlock()
+    return cp.done[target]
+}
+
+func (cp *checkpoint) record(target string, result Result) {
+    if cp == nil {
+        return
+    }
+
+    line, err := json.Marshal(checkpointEntry{Target: target, Result: result})
+    if err != nil {
+        return
+    }
+
+    cp.mu.Lock()
+    defer cp.mu.Unlock()
+
+    cp.done[target] = true
+    cp.writer.Write(append(line, '\n'))
+    if time.Since(cp.lastFlush) >= checkpointFlushInterval {
+        cp.writer.Flush()
+        cp.lastFlush = time.Now()
+    }
+}
+
+func (cp *checkpoint) Close() error {
+    if cp == nil {
+        return nil
+    }
+
+    cp.mu.Lock()
+    defer cp.mu.Unlock()
+
+    if err := cp.writer.Flush(); err != nil {
+        cp.file.Close()
+        return err
+    }
+    return cp.file.Close()
+}
+
+func interruptChannel() <-chan struct{} {
+    stop := make(chan struct{})
+    signals := make(chan os.Signal, 2)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+    go func() {
+        <-signals
+        color.Yellow("\n[~] Interrupted, waiting for running checks to finish (press Ctrl+C again to quit now)")
+        close(stop)
+
+        <-signals
+        color.Red("[-] Aborted")
+        os.Exit(130)
+    }()
+
+    return stop
+}
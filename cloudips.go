@@ -0,0 +1,372 @@
+package main
+
+import (
+    "bytes"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/fatih/color"
+)
+
+const (
+    awsRangesURL    = "https://ip-ranges.amazonaws.com/ip-ranges.json"
+    gcpRangesURL    = "https://www.gstatic.com/ipranges/cloud.json"
+    azureRangesPage = "https://www.microsoft.com/en-us/download/details.aspx?id=56519"
+)
+
+type cloudRange struct {
+    network  *net.IPNet
+    provider string
+    region   string
+    service  string
+    start    net.IP
+    end      net.IP
+    maxEnd   net.IP
+}
+
+var (
+    cloudRanges        []cloudRange
+    azureDownloadRegex = regexp.MustCompile(`https://download\.microsoft\.com/[^"']+ServiceTags_Public_\d+\.json`)
+)
+
+type awsRanges struct {
+    Prefixes []struct {
+        IPPrefix string `json:"ip_prefix"`
+        Region   string `json:"region"`
+        Service  string `json:"service"`
+    } `json:"prefixes"`
+    IPv6Prefixes []struct {
+        IPv6Prefix string `json:"ipv6_prefix"`
+        Region     string `json:"region"`
+        Service    string `json:"service"`
+    } `json:"ipv6_prefixes"`
+}
+
+type gcpRanges struct {
+    Prefixes []struct {
+        IPv4Prefix string `json:"ipv4Prefix"`
+        IPv6Prefix string `json:"ipv6Prefix"`
+        Service    string `json:"service"`
+        Scope      string `json:"scope"`
+    } `json:"prefixes"`
+}
+
+type azureRanges struct {
+    Values []struct {
+        Name       string `json:"name"`
+        Properties struct {
+            Region          string   `json:"region"`
+            SystemService   string   `json:"systemService"`
+            AddressPrefixes []string `json:"addressPrefixes"`
+        } `json:"properties"`
+    } `json:"values"`
+}
+
+func loadCloudRanges(dir string) error {
+    files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+    if err != nil {
+        return err
+    }
+    if len(files) == 0 {
+        return fmt.Errorf("no cloud range files in %s (run with -update-ranges first)", dir)
+    }
+
+    var ranges []cloudRange
+    for _, file := range files {
+        data, err := os.ReadFile(file)
+        if err != nil {
+            return fmt.Errorf("reading %s: %v", file, err)
+        }
+
+        parsed, err := parseCloudRanges(data)
+        if err != nil {
+            return fmt.Errorf("parsing %s: %v", file, err)
+        }
+        ranges = append(ranges, parsed...)
+    }
+
+    cloudRanges = sortCloudRanges(ranges)
+    return nil
+}
+
+func parseCloudRanges(data []byte) ([]cloudRange, error) {
+    var probe map[string]json.RawMessage
+    if err := json.Unmarshal(data, &probe); err != nil {
+        return nil, err
+    }
+
+    var ranges []cloudRange
+    add := func(prefix, provider, region, service string) {
+        if _, network, err := net.ParseCIDR(prefix); err == nil {
+            end := make(net.IP, len(network.IP))
+            for i := range network.IP {
+                end[i] = network.IP[i] | ^network.Mask[i]
+            }
+            ranges = append(ranges, cloudRange{
+                network:  network,
+                provider: provider,
+                region:   region,
+                service:  service,
+                start:    network.IP.To16(),
+                end:      end.To16(),
+            })
+        }
+    }
+
+    switch {
+    case probe["ipv6_prefixes"] != nil:
+        var aws awsRanges
+        if err := json.Unmarshal(data, &aws); err != nil {
+            return nil, err
+        }
+        for _, p := range aws.Prefixes {
+            if p.Service == "EC2" {
+                add(p.IPPrefix, "AWS", p.Region, p.Service)
+            }
+        }
+        for _, p := range aws.IPv6Prefixes {
+            if p.Service == "EC2" {
+                add(p.IPv6Prefix, "AWS", p.Region, p.Service)
+            }
+        }
+    case probe["values"] != nil:
+        var azure azureRanges
+        if err := json.Unmarshal(data, &azure); err != nil {
+            return nil, err
+        }
+        for _, v := range azure.Values {
+            if !strings.HasPrefix(v.Name, "AzureCloud.") {
+                continue
+            }
+            for _, prefix := range v.Properties.AddressPrefixes {
+                add(prefix, "Azure", v.Properties.Region, "AzureCloud")
+            }
+        }
+    case probe["prefixes"] != nil:
+        var gcp gcpRanges
+        if err := json.Unmarshal(data, &gcp); err != nil {
+            return nil, err
+        }
+        for _, p := range gcp.Prefixes {
+            prefix := p.IPv4Prefix
+            if prefix == "" {
+                prefix = p.IPv6Prefix
+            }
+            add(prefix, "GCP", p.Scope, p.Service)
+        }
+    default:
+        return nil, fmt.Errorf("unknown cloud range format")
+    }
+
+    return ranges, nil
+}
+
+func sortCloudRanges(ranges []cloudRange) []cloudRange {
+    sort.Slice(ranges, func(i, j int) bool {
+        if c := bytes.Compare(ranges[i].start, ranges[j].start); c != 0 {
+            return c < 0
+        }
+        return bytes.Compare(ranges[i].end, ranges[j].end) > 0
+    })
+
+    var maxEnd net.IP
+    for i := range ranges {
+        if maxEnd == nil || bytes.Compare(ranges[i].end, maxEnd) > 0 {
+            maxEnd = ranges[i].end
+        }
+        ranges[i].maxEnd = maxEnd
+    }
+
+    return ranges
+}
+
+func lookupCloudRange(ip net.IP) (cloudRange, bool) {
+    ip = ip.To16()
+    if ip == nil {
+        return cloudRange{}, false
+    }
+
+    i := sort.Search(len(cloudRanges), func(i int) bool {
+        return bytes.Compare(cloudRanges[i].start, ip) > 0
+    }) - 1
+
+    for ; i >= 0 && bytes.Compare(cloudRanges[i].maxEnd, ip) >= 0; i-- {
+        if cloudRanges[i].network.Contains(ip) {
+            return cloudRanges[i], true
+        }
+    }
+    return cloudRange{}, false
+}
+
+func checkCloudIPs(target scanTarget, result *Result) {
+    subdomain := target.name
+    for _, ip := range targetIPs(target) {
+        r, ok := lookupCloudRange(ip)
+        if !ok {
+            continue
+        }
+
+        result.IP = ip.String()
+        result.CloudProvider = r.provider
+        result.CloudRegion = r.region
+
+        serving, service, confidence, evidence := probeCloudIP(subdomain, ip)
+        if serving {
+            return
+        }
+        if service == "" {
+            service = fmt.Sprintf("%s %s", r.provider, r.service)
+        }
+
+        result.Status = "potentially_vulnerable"
+        result.Service = service
+        result.Confidence = confidence
+        result.RecordType = "A"
+        if ip.To4() == nil {
+            result.RecordType = "AAAA"
+        }
+        result.Evidence = fmt.Sprintf("%s IP %s (%s) %s", r.provider, ip, r.region, evidence)
+        return
+    }
+}
+
+func probeCloudIP(host string, ip net.IP) (bool, string, string, string) {
+    timeout := time.Duration(config.Timeout) * time.Second
+    addr := net.JoinHostPort(ip.String(), "443")
+
+    tlsEvidence := ""
+    conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, &tls.Config{
+        ServerName:         host,
+        InsecureSkipVerify: true,
+    })
+    if err == nil {
+        certs := conn.ConnectionState().PeerCertificates
+        conn.Close()
+
+        if len(certs) > 0 {
+            intermediates := x509.NewCertPool()
+            for _, cert := range certs[1:] {
+                intermediates.AddCert(cert)
+            }
+            if certs[0].VerifyHostname(host) == nil {
+                return true, "", "", ""
+            }
+            if _, err := certs[0].Verify(x509.VerifyOptions{Intermediates: intermediates}); err == nil {
+                return true, "", "", ""
+            }
+            tlsEvidence = fmt.Sprintf("serves an untrusted TLS certificate for %s, not %s", strings.Join(append([]string{certs[0].Subject.CommonName}, certs[0].DNSNames...), ","), host)
+        }
+    }
+
+    req, err := http.NewRequest("GET", "http://"+net.JoinHostPort(ip.String(), "80")+"/", nil)
+    if err != nil {
+        return false, "", "low", err.Error()
+    }
+    req.Host = host
+    req.Header.Set("User-Agent", config.UserAgent)
+
+    client := &http.Client{
+        Timeout:   timeout,
+        Transport: httpClient.Transport,
+        CheckRedirect: func(*http.Request, []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        if tlsEvidence != "" {
+            return false, "", "low", tlsEvidence + " and does not answer HTTP"
+        }
+        return false, "", "medium", "does not answer HTTP or TLS"
+    }
+    defer resp.Body.Close()
+
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+    response := &httpResponse{status: resp.StatusCode, body: string(body), location: resp.Header.Get("Location")}
+    for key, values := range resp.Header {
+        for _, value := range values {
+            response.headers = append(response.headers, key+": "+value)
+        }
+    }
+
+    for _, sig := range signatures {
+        if sig.hasMatcherType("favicon") || !(sig.hasMatcherType("word") || sig.hasMatcherType("regex") || sig.hasMatcherType("header")) {
+            continue
+        }
+        if matched, evidence := evaluateMatchers(sig, response, "http://"+host); matched {
+            return false, sig.Service, "medium", fmt.Sprintf("answers HTTP %d with the %s fingerprint (%s)", resp.StatusCode, sig.Service, strings.Join(evidence, " | "))
+        }
+    }
+
+    return true, "", "", ""
+}
+
+func updateCloudRanges(dir string) error {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+
+    sources := map[string]string{
+        "aws.json": awsRangesURL,
+        "gcp.json": gcpRangesURL,
+    }
+
+    page, err := download(azureRangesPage)
+    if err != nil {
+        color.Yellow("[~] Could not fetch Azure service tags page: %v", err)
+    } else if url := azureDownloadRegex.FindString(string(page)); url != "" {
+        sources["azure.json"] = url
+    } else {
+        color.Yellow("[~] Azure service tags download link not found, skipping Azure")
+    }
+
+    for name, url := range sources {
+        data, err := download(url)
+        if err != nil {
+            return fmt.Errorf("downloading %s: %v", url, err)
+        }
+        if _, err := parseCloudRanges(data); err != nil {
+            return fmt.Errorf("validating %s: %v", url, err)
+        }
+        if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+            return err
+        }
+        color.Green("[+] Updated %s from %s", filepath.Join(dir, name), url)
+    }
+
+    return nil
+}
+
+func download(url string) ([]byte, error) {
+    client := &http.Client{Timeout: 2 * time.Minute}
+    resp, err := client.Get(url)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("unexpected status %s", resp.Status)
+    }
+    return io.ReadAll(resp.Body)
+}
+
+func defaultCloudRangesDir() string {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "ranges"
+    }
+    return filepath.Join(home, ".subtake", "ranges")
+}
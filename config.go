@@ -0,0 +1,212 @@
+package main
+
+import (
+    "bytes"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/valyala/fasthttp"
+)
+
+const maxRedirects = 10
+
+var configEnvVars = map[string]string{
+    "threads":            "SUBTAKE_THREADS",
+    "timeout":            "SUBTAKE_TIMEOUT",
+    "user_agent":         "SUBTAKE_USER_AGENT",
+    "follow_redirects":   "SUBTAKE_FOLLOW_REDIRECTS",
+    "verify_ssl":         "SUBTAKE_VERIFY_SSL",
+    "deep_check":         "SUBTAKE_DEEP_CHECK",
+    "output_file":        "SUBTAKE_OUTPUT_FILE",
+    "custom_signatures":  "SUBTAKE_CUSTOM_SIGNATURES",
+    "replace_signatures": "SUBTAKE_REPLACE_SIGNATURES",
+    "resolvers_file":     "SUBTAKE_RESOLVERS_FILE",
+    "trusted_resolver":   "SUBTAKE_TRUSTED_RESOLVER",
+    "resolver_rate":      "SUBTAKE_RESOLVER_RATE",
+    "dns_retries":        "SUBTAKE_DNS_RETRIES",
+    "dns_timeout":        "SUBTAKE_DNS_TIMEOUT",
+    "ns_check":           "SUBTAKE_NS_CHECK",
+    "record_checks":      "SUBTAKE_RECORD_CHECKS",
+    "cloud_ip_check":     "SUBTAKE_CLOUD_IP_CHECK",
+    "cloud_ranges_dir":   "SUBTAKE_CLOUD_RANGES_DIR",
+    "wildcard_check":     "SUBTAKE_WILDCARD_CHECK",
+    "database":           "SUBTAKE_DB",
+}
+
+func defaultConfig() Config {
+    return Config{
+        Threads:         50,
+        Timeout:         10,
+        UserAgent:       "SubTake/v2.0",
+        FollowRedirects: true,
+        VerifySSL:       false,
+        DeepCheck:       true,
+        OutputFile:      "",
+        DNSRetries:      2,
+        DNSTimeout:      3,
+        CloudRangesDir:  defaultCloudRangesDir(),
+        WildcardCheck:   true,
+    }
+}
+
+func loadConfigFile(cfg *Config, filename string) error {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return fmt.Errorf("reading config file: %v", err)
+    }
+
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(cfg); err != nil {
+        return fmt.Errorf("parsing config file %s: %v", filename, err)
+    }
+
+    return nil
+}
+
+func applyEnvConfig(cfg *Config) error {
+    for key, env := range configEnvVars {
+        value, ok := os.LookupEnv(env)
+        if !ok {
+            continue
+        }
+
+        var err error
+        switch key {
+        case "threads":
+            cfg.Threads, err = strconv.Atoi(value)
+        case "timeout":
+            cfg.Timeout, err = strconv.Atoi(value)
+        case "user_agent":
+            cfg.UserAgent = value
+        case "follow_redirects":
+            cfg.FollowRedirects, err = strconv.ParseBool(value)
+        case "verify_ssl":
+            cfg.VerifySSL, err = strconv.ParseBool(value)
+        case "deep_check":
+            cfg.DeepCheck, err = strconv.ParseBool(value)
+        case "output_file":
+            cfg.OutputFile = value
+        case "custom_signatures":
+            cfg.CustomSignatures = splitList(value)
+        case "replace_signatures":
+            cfg.ReplaceSignatures, err = strconv.ParseBool(value)
+        case "resolvers_file":
+            cfg.ResolversFile = value
+        case "trusted_resolver":
+            cfg.TrustedResolver = value
+        case "resolver_rate":
+            cfg.ResolverRate, err = strconv.Atoi(value)
+        case "dns_retries":
+            cfg.DNSRetries, err = strconv.Atoi(value)
+        case "dns_timeout":
+            cfg.DNSTimeout, err = strconv.Atoi(value)
+        case "ns_check":
+            cfg.NSCheck, err = strconv.ParseBool(value)
+        case "record_checks":
+            cfg.RecordChecks = splitList(value)
+        case "cloud_ip_check":
+            cfg.CloudIPCheck, err = strconv.ParseBool(value)
+        case "cloud_ranges_dir":
+            cfg.CloudRangesDir = value
+        case "wildcard_check":
+            cfg.WildcardCheck, err = strconv.ParseBool(value)
+        case "database":
+            cfg.Database = value
+        }
+
+        if err != nil {
+            return fmt.Errorf("invalid value %q for %s: %v", value, env, err)
+        }
+    }
+
+    return nil
+}
+
+func validateConfig(cfg Config) error {
+    if cfg.Threads <= 0 {
+        return fmt.Errorf("threads must be greater than 0 (got %d)", cfg.Threads)
+    }
+    if cfg.Timeout <= 0 {
+        return fmt.Errorf("timeout must be greater than 0 seconds (got %d)", cfg.Timeout)
+    }
+    if strings.TrimSpace(cfg.UserAgent) == "" {
+        return fmt.Errorf("user_agent must not be empty")
+    }
+    if cfg.ResolverRate < 0 {
+        return fmt.Errorf("resolver_rate must not be negative (got %d)", cfg.ResolverRate)
+    }
+    if cfg.DNSRetries < 0 {
+        return fmt.Errorf("dns_retries must not be negative (got %d)", cfg.DNSRetries)
+    }
+    if cfg.DNSTimeout <= 0 {
+        return fmt.Errorf("dns_timeout must be greater than 0 seconds (got %d)", cfg.DNSTimeout)
+    }
+    if cfg.ResolversFile != "" {
+        if _, err := os.Stat(cfg.ResolversFile); err != nil {
+            return fmt.Errorf("resolvers file: %v", err)
+        }
+    }
+    if cfg.TrustedResolver != "" {
+        if _, err := normalizeResolver(cfg.TrustedResolver); err != nil {
+            return err
+        }
+    }
+    for _, check := range cfg.RecordChecks {
+        if !recordCheckTypes[strings.ToLower(check)] && !strings.EqualFold(check, "all") {
+            return fmt.Errorf("unknown record check %q (valid: mx, spf, srv, txt, all)", check)
+        }
+    }
+    if cfg.ReplaceSignatures && len(cfg.CustomSignatures) == 0 {
+        return fmt.Errorf("replace_signatures requires at least one custom signature source")
+    }
+    for _, path := range cfg.CustomSignatures {
+        if _, err := os.Stat(path); err != nil {
+            return fmt.Errorf("custom signature source %s: %v", path, err)
+        }
+    }
+
+    return nil
+}
+
+func splitList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        item = strings.TrimSpace(item)
+        if item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+func setupClients() {
+    timeout := time.Duration(config.Timeout) * time.Second
+
+    client = &fasthttp.Client{
+        ReadTimeout:                   timeout,
+        WriteTimeout:                  timeout,
+        MaxConnsPerHost:               100,
+        TLSConfig:                     &tls.Config{InsecureSkipVerify: !config.VerifySSL},
+        DisableHeaderNamesNormalizing: true,
+    }
+
+    httpClient = &http.Client{
+        Timeout: timeout,
+        Transport: &http.Transport{
+            TLSClientConfig: &tls.Config{InsecureSkipVerify: !config.VerifySSL},
+        },
+    }
+
+    if !config.FollowRedirects {
+        httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+            return http.ErrUseLastResponse
+        }
+    }
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+const exitNewVulnerabilities = 3
+
+var textResultRegex = regexp.MustCompile(`^\[(VULNERABLE|POTENTIAL|WILDCARD|SAFE)\] (\S+)(?: -> (.*?) \(([^()]*)\)(?: \[(high|medium|low|)\])?(?: (.*))?)?$`)
+
+var textStatuses = map[string]string{
+    "VULNERABLE": "vulnerable",
+    "POTENTIAL":  "potentially_vulnerable",
+    "WILDCARD":   "wildcard",
+    "SAFE":       "safe",
+}
+
+type resultChange struct {
+    Subdomain string   `json:"subdomain"`
+    Old       Result   `json:"old"`
+    New       Result   `json:"new"`
+    Changes   []string `json:"changes"`
+}
+
+type scanDiff struct {
+    New       []Result       `json:"new"`
+    Resolved  []Result       `json:"resolved"`
+    Missing   []Result       `json:"missing"`
+    Changed   []resultChange `json:"changed"`
+    Unchanged []Result       `json:"unchanged"`
+}
+
+func runDiffCommand(args []string) {
+    fs := flag.NewFlagSet("diff", flag.ExitOnError)
+    jsonOutput := fs.Bool("json", false, "Print the diff as JSON instead of text")
+    diffOutput := fs.String("o", "", "Also write the diff as JSON to this file")
+    verbose := fs.Bool("v", false, "List unchanged findings too")
+    fs.Usage = func() {
+        fmt.Fprintln(os.Stderr, "Usage: subtake diff [-json] [-o diff.json] [-v] old.json new.json")
+        fs.PrintDefaults()
+    }
+    fs.Parse(args)
+
+    if fs.NArg() != 2 {
+        fs.Usage()
+        os.Exit(1)
+    }
+
+    old, err := readResultsFile(fs.Arg(0))
+    if err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+    current, err := readResultsFile(fs.Arg(1))
+    if err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+
+    d := diffResults(old, current)
+    if *jsonOutput {
+        jsonData, _ := json.MarshalIndent(d, "", "  ")
+        fmt.Println(string(jsonData))
+    } else {
+        printDiff(d, *verbose)
+    }
+
+    if err := finishDiff(d, *diffOutput); err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+}
+
+func finishDiff(d scanDiff, diffOutput string) error {
+    if diffOutput != "" {
+        jsonData, _ := json.MarshalIndent(d, "", "  ")
+        if err := os.WriteFile(diffOutput, append(jsonData, '\n'), 0644); err != nil {
+            return fmt.Errorf("writing diff: %v", err)
+        }
+        color.Green("[+] Diff saved to: %s", diffOutput)
+    }
+
+    if d.newVulnerabilities() > 0 {
+        os.Exit(exitNewVulnerabilities)
+    }
+    return nil
+}
+
+func compareBaseline(baselineFile string, baseline []Result, diffOutput string) {
+    if baselineFile == "" {
+        return
+    }
+
+    color.Cyan("\n[+] Changes since %s:", baselineFile)
+    d := diffResults(baseline, results)
+    printDiff(d, false)
+
+    if err := finishDiff(d, diffOutput); err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+}
+
+func readResultsFile(filename string) ([]Result, error) {
+    input, err := openTargets(filename)
+    if err != nil {
+        return nil, fmt.Errorf("opening results file: %v", err)
+    }
+    defer input.Close()
+
+    data, err := io.ReadAll(input)
+    if err != nil {
+        return nil, fmt.Errorf("reading %s: %v", filename, err)
+    }
+
+    var results []Result
+    trimmed := bytes.TrimSpace(data)
+    switch {
+    case len(trimmed) == 0:
+    case textResultRegex.Match(bytes.SplitN(trimmed, []byte("\n"), 2)[0]):
+        results, err = parseTextResults(trimmed)
+    case trimmed[0] == '[':
+        err = json.Unmarshal(trimmed, &results)
+    case trimmed[0] == '{':
+        results, err = parseJSONLResults(trimmed)
+    default:
+        results, err = parseCSVResults(trimmed)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("parsing %s: %v", filename, err)
+    }
+    for _, result := range results {
+        if result.Subdomain == "" {
+            return nil, fmt.Errorf("parsing %s: not a SubTake results file (result without subdomain)", filename)
+        }
+    }
+    return results, nil
+}
+
+func parseJSONLResults(data []byte) ([]Result, error) {
+    var results []Result
+
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    scanner.Buffer(make([]byte, 64*1024), maxInputLine)
+    for scanner.Scan() {
+        line := bytes.TrimSpace(scanner.Bytes())
+        if len(line) == 0 {
+            continue
+        }
+
+        // Checkpoint files wrap each result with the target it belongs to.
+        var entry struct {
+            Target string  `json:"target"`
+            Result *Result `json:"result"`
+        }
+        if err := json.Unmarshal(line, &entry); err != nil {
+            return nil, err
+        }
+        if entry.Result != nil {
+            results = append(results, *entry.Result)
+            continue
+        }
+
+        var result Result
+        if err := json.Unmarshal(line, &result); err != nil {
+            return nil, err
+        }
+        results = append(results, result)
+    }
+
+    return results, scanner.Err()
+}
+
+func parseCSVResults(data []byte) ([]Result, error) {
+    reader := csv.NewReader(bytes.NewReader(data))
+    reader.FieldsPerRecord = -1
+    reader.LazyQuotes = true
+    rows, err := reader.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    if len(rows) == 0 {
+        return nil, nil
+    }
+
+    if rows[0][0] != "subdomain" {
+        return parseLegacyCSVResults(rows)
+    }
+
+    columns := make(map[string]int)
+    for i, name := range rows[0] {
+        columns[name] = i
+    }
+
+    var results []Result
+    for _, row := range rows[1:] {
+        field := func(name string) string {
+            if i, ok := columns[name]; ok && i < len(row) {
+                return row[i]
+            }
+            return ""
+        }
+
+        result := Result{
+            Subdomain:     field("subdomain"),
+            CNAME:         field("cname"),
+            Service:       field("service"),
+            Status:        field("status"),
+            Confidence:    field("confidence"),
+            Evidence:      field("evidence"),
+            IP:            field("ip"),
+            RecordType:    field("record_type"),
+            CloudProvider: field("cloud_provider"),
+            CloudRegion:   field("cloud_region"),
+            ZoneID:        field("zone_id"),
+            Resource:      field("resource"),
+            Snippet:       field("snippet"),
+        }
+        result.ResponseTime, _ = strconv.ParseInt(field("response_time"), 10, 64)
+        result.HTTPStatus, _ = strconv.Atoi(field("http_status"))
+        results = append(results, result)
+    }
+    return results, nil
+}
+
+func parseLegacyCSVResults(rows [][]string) ([]Result, error) {
+    var results []Result
+    for i, row := range rows {
+        if len(row) < 6 {
+            return nil, fmt.Errorf("not a SubTake results file (line %d has %d columns, need subdomain,cname,service,status,confidence,evidence)", i+1, len(row))
+        }
+        results = append(results, Result{
+            Subdomain:  row[0],
+            CNAME:      row[1],
+            Service:    row[2],
+            Status:     row[3],
+            Confidence: row[4],
+            Evidence:   strings.Join(row[5:], ","),
+        })
+    }
+    return results, nil
+}
+
+func parseTextResults(data []byte) ([]Result, error) {
+    var results []Result
+
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    scanner.Buffer(make([]byte, 64*1024), maxInputLine)
+    for line := 1; scanner.Scan(); line++ {
+        text := strings.TrimRight(scanner.Text(), "\r")
+        if strings.TrimSpace(text) == "" {
+            continue
+        }
+
+        m := textResultRegex.FindStringSubmatch(text)
+        if m == nil {
+            return nil, fmt.Errorf("not a SubTake results file (line %d is not a [STATUS] result)", line)
+        }
+
+        result := Result{
+            Subdomain:  m[2],
+            Status:     textStatuses[m[1]],
+            Service:    m[4],
+            Confidence: m[5],
+            Evidence:   m[6],
+        }
+
+        target := m[3]
+        fields := strings.Fields(target)
+        switch {
+        case len(fields) == 2 && fields[0] == "NS":
+            result.RecordType = "NS"
+            result.NameServers = strings.Split(fields[1], ",")
+        case len(fields) == 2 && fields[1] == "record":
+            result.RecordType = fields[0]
+        case len(fields) == 2 && (fields[0] == "A" || fields[0] == "AAAA"):
+            result.RecordType = fields[0]
+            result.IP = fields[1]
+        default:
+            result.CNAME = target
+        }
+        results = append(results, result)
+    }
+
+    return results, scanner.Err()
+}
+
+func diffRecordType(result Result) string {
+    if result.RecordType == "" {
+        return "CNAME"
+    }
+    return result.RecordType
+}
+
+func diffKey(result Result) string {
+    key := strings.ToLower(result.Subdomain) + "|" + diffRecordType(result)
+    if result.Resource != "" {
+        key += "|" + result.Resource
+    }
+    return key
+}
+
+func diffResults(old, current []Result) scanDiff {
+    index := func(results []Result) map[string]Result {
+        m := make(map[string]Result)
+        for _, result := range results {
+            key := diffKey(result)
+            if kept, ok := m[key]; ok && statusRank(kept.Status) >= statusRank(result.Status) {
+                continue
+            }
+            m[key] = result
+        }
+        return m
+    }
+    before, after := index(old), index(current)
+
+    rescanned := make(map[string]bool)
+    for _, result := range current {
+        rescanned[strings.ToLower(result.Subdomain)] = true
+    }
+
+    var names []string
+    for name := range before {
+        names = append(names, name)
+    }
+    for name := range after {
+        if _, ok := before[name]; !ok {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+
+    d := scanDiff{New: []Result{}, Resolved: []Result{}, Missing: []Result{}, Changed: []resultChange{}, Unchanged: []Result{}}
+    for _, name := range names {
+        was, inOld := before[name]
+        now, inNew := after[name]
+        wasFinding := inOld && isFinding(was)
+        isNow := inNew && isFinding(now)
+
+        switch {
+        case isNow && !wasFinding:
+            d.New = append(d.New, now)
+        case wasFinding && !inNew && !rescanned[strings.ToLower(was.Subdomain)]:
+            d.Missing = append(d.Missing, was)
+        case wasFinding && !isNow:
+            d.Resolved = append(d.Resolved, was)
+        case wasFinding && isNow:
+            if changes := resultChanges(was, now); len(changes) > 0 {
+                d.Changed = append(d.Changed, resultChange{Subdomain: now.Subdomain, Old: was, New: now, Changes: changes})
+            } else {
+                d.Unchanged = append(d.Unchanged, now)
+            }
+        }
+    }
+
+    return d
+}
+
+func isFinding(result Result) bool {
+    return result.Status == "vulnerable" || result.Status == "potentially_vulnerable"
+}
+
+func resultChanges(was, now Result) []string {
+    var changes []string
+    for _, field := range []struct{ name, old, new string }{
+        {"status", was.Status, now.Status},
+        {"service", was.Service, now.Service},
+        {"confidence", was.Confidence, now.Confidence},
+        {"target", resultTarget(was), resultTarget(now)},
+    } {
+        if field.old != field.new {
+            changes = append(changes, fmt.Sprintf("%s %s -> %s", field.name, orNone(field.old), orNone(field.new)))
+        }
+    }
+    return changes
+}
+
+func orNone(value string) string {
+    if value == "" {
+        return "none"
+    }
+    return value
+}
+
+func (d scanDiff) newVulnerabilities() int {
+    count := 0
+    for _, result := range d.New {
+        if result.Status == "vulnerable" {
+            count++
+        }
+    }
+    for _, change := range d.Changed {
+        if change.New.Status == "vulnerable" && change.Old.Status != "vulnerable" {
+            count++
+        }
+    }
+    return count
+}
+
+func printDiff(d scanDiff, verbose bool) {
+    color.Red("[+] New: %d", len(d.New))
+    for _, result := range d.New {
+        fmt.Println("    + " + formatResult(result))
+    }
+
+    color.Green("[-] Resolved: %d", len(d.Resolved))
+    for _, result := range d.Resolved {
+        fmt.Printf("    - %s (%s)\n", result.Subdomain, result.Service)
+    }
+
+    color.Yellow("[?] Not rescanned: %d", len(d.Missing))
+    for _, result := range d.Missing {
+        fmt.Printf("    ? %s (%s)\n", result.Subdomain, result.Service)
+    }
+
+    color.Yellow("[~] Changed: %d", len(d.Changed))
+    for _, change := range d.Changed {
+        fmt.Printf("    ~ %s: %s\n", change.Subdomain, strings.Join(change.Changes, "; "))
+    }
+
+    color.Cyan("[=] Unchanged: %d", len(d.Unchanged))
+    if verbose {
+        for _, result := range d.Unchanged {
+            fmt.Println("    = " + formatResult(result))
+        }
+    }
+
+    if n := d.newVulnerabilities(); n > 0 {
+        color.Red("[!] New vulnerabilities: %d", n)
+    }
+}
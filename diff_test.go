@@ -0,0 +1,214 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func writeResultsFixture(t *testing.T, name, data string) string {
+    t.Helper()
+
+    filename := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return filename
+}
+
+func TestReadResultsFile(t *testing.T) {
+    tests := []struct {
+        name string
+        data string
+    }{
+        {
+            name: "results.json",
+            data: `[
+  {"subdomain": "www.example.com", "cname": "gone.herokuapp.com", "service": "Heroku", "status": "vulnerable", "confidence": "high", "evidence": "No such app"},
+  {"subdomain": "mail.example.com", "service": "Unregistered domain", "status": "potentially_vulnerable", "confidence": "medium", "evidence": "Dangling MX", "record_type": "MX"},
+  {"subdomain": "ok.example.com", "status": "safe"}
+]`,
+        },
+        {
+            name: "results.jsonl",
+            data: `{"subdomain":"www.example.com","cname":"gone.herokuapp.com","service":"Heroku","status":"vulnerable","confidence":"high","evidence":"No such app"}
+{"subdomain":"mail.example.com","service":"Unregistered domain","status":"potentially_vulnerable","confidence":"medium","evidence":"Dangling MX","record_type":"MX"}
+
+{"subdomain":"ok.example.com","status":"safe"}
+`,
+        },
+        {
+            name: "checkpoint.jsonl",
+            data: `{"target":"www.example.com","result":{"subdomain":"www.example.com","cname":"gone.herokuapp.com","service":"Heroku","status":"vulnerable","confidence":"high","evidence":"No such app"}}
+{"target":"mail.example.com","result":{"subdomain":"mail.example.com","service":"Unregistered domain","status":"potentially_vulnerable","confidence":"medium","evidence":"Dangling MX","record_type":"MX"}}
+{"target":"ok.example.com","result":{"subdomain":"ok.example.com","status":"safe"}}
+`,
+        },
+        {
+            name: "results.csv",
+            data: strings.Join(csvHeader, ",") + `
+www.example.com,gone.herokuapp.com,Heroku,vulnerable,high,No such app,,120,,,,,,404,
+mail.example.com,,Unregistered domain,potentially_vulnerable,medium,Dangling MX,,,MX,,,,,,
+ok.example.com,,,safe,,,,,,,,,,,
+`,
+        },
+        {
+            name: "legacy.txt",
+            data: `www.example.com,gone.herokuapp.com,Heroku,vulnerable,high,No such app
+mail.example.com,,Unregistered domain,potentially_vulnerable,medium,Dangling MX
+ok.example.com,,,safe,,
+`,
+        },
+        {
+            name: "results.txt",
+            data: `[VULNERABLE] www.example.com -> gone.herokuapp.com (Heroku) [high] No such app
+[POTENTIAL] mail.example.com -> MX record (Unregistered domain) [medium] Dangling MX
+[SAFE] ok.example.com
+`,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            results, err := readResultsFile(writeResultsFixture(t, tt.name, tt.data))
+            if err != nil {
+                t.Fatalf("readResultsFile: %v", err)
+            }
+            if len(results) != 3 {
+                t.Fatalf("got %d results, want 3: %+v", len(results), results)
+            }
+
+            www, mail, ok := results[0], results[1], results[2]
+            if www.Subdomain != "www.example.com" || www.CNAME != "gone.herokuapp.com" || www.Service != "Heroku" ||
+                www.Status != "vulnerable" || www.Confidence != "high" || www.Evidence != "No such app" {
+                t.Errorf("www: got %+v", www)
+            }
+            if mail.Status != "potentially_vulnerable" || mail.Service != "Unregistered domain" || mail.Evidence != "Dangling MX" {
+                t.Errorf("mail: got %+v", mail)
+            }
+            if tt.name != "legacy.txt" && mail.RecordType != "MX" {
+                t.Errorf("mail: record type %q, want MX", mail.RecordType)
+            }
+            if ok.Subdomain != "ok.example.com" || ok.Status != "safe" {
+                t.Errorf("ok: got %+v", ok)
+            }
+        })
+    }
+}
+
+func TestReadResultsFileTextTargets(t *testing.T) {
+    data := `[VULNERABLE] ns.example.com -> NS ns-1.awsdns-00.com.,ns-2.awsdns-00.net. (AWS Route 53) [high] lame delegation
+[POTENTIAL] ip.example.com -> A 192.0.2.1 (AWS EC2) [low] untrusted certificate
+[WILDCARD] *.example.com -> wild.herokuapp.com (Heroku) Wildcard answers everything
+[POTENTIAL] empty.example.com ->  (Unknown) [] no target
+`
+    results, err := readResultsFile(writeResultsFixture(t, "results.txt", data))
+    if err != nil {
+        t.Fatalf("readResultsFile: %v", err)
+    }
+    if len(results) != 4 {
+        t.Fatalf("got %d results, want 4", len(results))
+    }
+
+    for i, want := range []Result{
+        {Subdomain: "ns.example.com", Status: "vulnerable", RecordType: "NS", NameServers: []string{"ns-1.awsdns-00.com.", "ns-2.awsdns-00.net."}, Service: "AWS Route 53", Confidence: "high", Evidence: "lame delegation"},
+        {Subdomain: "ip.example.com", Status: "potentially_vulnerable", RecordType: "A", IP: "192.0.2.1", Service: "AWS EC2", Confidence: "low", Evidence: "untrusted certificate"},
+        {Subdomain: "*.example.com", Status: "wildcard", CNAME: "wild.herokuapp.com", Service: "Heroku", Evidence: "Wildcard answers everything"},
+        {Subdomain: "empty.example.com", Status: "potentially_vulnerable", Service: "Unknown", Evidence: "no target"},
+    } {
+        got := results[i]
+        if formatResult(got) != formatResult(want) || got.RecordType != want.RecordType {
+            t.Errorf("line %d: got %q (%s), want %q (%s)", i+1, formatResult(got), got.RecordType, formatResult(want), want.RecordType)
+        }
+    }
+}
+
+func TestReadResultsFileRejectsOtherFiles(t *testing.T) {
+    tests := []struct {
+        name string
+        data string
+    }{
+        {"targets.txt", "www.example.com\napi.example.com\n"},
+        {"mixed.txt", "[VULNERABLE] www.example.com -> gone.herokuapp.com (Heroku) [high] No such app\nnot a result\n"},
+        {"other.json", `[{"host": "www.example.com"}]`},
+    }
+
+    for _, tt := range tests {
+        if _, err := readResultsFile(writeResultsFixture(t, tt.name, tt.data)); err == nil {
+            t.Errorf("%s: accepted as a results file", tt.name)
+        }
+    }
+}
+
+func TestDiffResults(t *testing.T) {
+    old := []Result{
+        {Subdomain: "new.example.com", Status: "safe"},
+        {Subdomain: "fixed.example.com", CNAME: "gone.herokuapp.com", Service: "Heroku", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "gone.example.com", CNAME: "x.github.io", Service: "GitHub Pages", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "moved.example.com", CNAME: "a.herokuapp.com", Service: "Heroku", Status: "potentially_vulnerable", Confidence: "medium"},
+        {Subdomain: "same.example.com", CNAME: "b.herokuapp.com", Service: "Heroku", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "multi.example.com", CNAME: "c.herokuapp.com", Service: "Heroku", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "multi.example.com", RecordType: "MX", Service: "Unregistered domain", Status: "potentially_vulnerable", Confidence: "medium"},
+        {Subdomain: "tf.example.com", RecordType: "CNAME", Resource: "aws_route53_record.a", CNAME: "a.s3-website-us-east-1.amazonaws.com", Service: "AWS S3", Status: "potentially_vulnerable"},
+        {Subdomain: "tf.example.com", RecordType: "CNAME", Resource: "aws_route53_record.b", CNAME: "b.s3-website-us-east-1.amazonaws.com", Service: "AWS S3", Status: "potentially_vulnerable"},
+    }
+    current := []Result{
+        {Subdomain: "NEW.example.com", CNAME: "new.herokuapp.com", Service: "Heroku", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "fixed.example.com", Status: "safe"},
+        {Subdomain: "moved.example.com", CNAME: "a.azurewebsites.net", Service: "Azure", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "same.example.com", RecordType: "CNAME", CNAME: "b.herokuapp.com", Service: "Heroku", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "multi.example.com", CNAME: "c.herokuapp.com", Service: "Heroku", Status: "vulnerable", Confidence: "high"},
+        {Subdomain: "tf.example.com", RecordType: "CNAME", Resource: "aws_route53_record.a", CNAME: "a.s3-website-us-east-1.amazonaws.com", Service: "AWS S3", Status: "potentially_vulnerable"},
+        {Subdomain: "tf.example.com", RecordType: "CNAME", Resource: "aws_route53_record.b", CNAME: "b.s3-website-us-east-1.amazonaws.com", Service: "AWS S3", Status: "vulnerable"},
+    }
+
+    d := diffResults(old, current)
+
+    names := func(results []Result) string {
+        var out []string
+        for _, result := range results {
+            out = append(out, result.Subdomain+"/"+diffRecordType(result))
+        }
+        return strings.Join(out, ",")
+    }
+    var changed []string
+    for _, change := range d.Changed {
+        changed = append(changed, change.Subdomain+": "+strings.Join(change.Changes, "; "))
+    }
+
+    if got := names(d.New); got != "NEW.example.com/CNAME" {
+        t.Errorf("new = %s", got)
+    }
+    if got := names(d.Resolved); got != "fixed.example.com/CNAME,multi.example.com/MX" {
+        t.Errorf("resolved = %s", got)
+    }
+    if got := names(d.Missing); got != "gone.example.com/CNAME" {
+        t.Errorf("missing = %s", got)
+    }
+    if got := names(d.Unchanged); got != "multi.example.com/CNAME,same.example.com/CNAME,tf.example.com/CNAME" {
+        t.Errorf("unchanged = %s", got)
+    }
+    wantChanged := []string{
+        "moved.example.com: status potentially_vulnerable -> vulnerable; service Heroku -> Azure; confidence medium -> high; target a.herokuapp.com -> a.azurewebsites.net",
+        "tf.example.com: status potentially_vulnerable -> vulnerable",
+    }
+    if strings.Join(changed, "\n") != strings.Join(wantChanged, "\n") {
+        t.Errorf("changed =\n%s\nwant\n%s", strings.Join(changed, "\n"), strings.Join(wantChanged, "\n"))
+    }
+    if n := d.newVulnerabilities(); n != 3 {
+        t.Errorf("new vulnerabilities = %d, want 3", n)
+    }
+}
+
+func TestDiffResultsKeepsWorstDuplicate(t *testing.T) {
+    old := []Result{
+        {Subdomain: "www.example.com", CNAME: "a.herokuapp.com", Service: "Heroku", Status: "vulnerable"},
+        {Subdomain: "www.example.com", Status: "safe"},
+    }
+
+    d := diffResults(old, old)
+    if len(d.Unchanged) != 1 || len(d.Resolved) != 0 {
+        t.Errorf("a later safe duplicate hid the finding: %+v", d)
+    }
+}
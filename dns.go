@@ -0,0 +1,180 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "net"
+    "strings"
+    "time"
+
+    "github.com/miekg/dns"
+)
+
+const maxCNAMEDepth = 10
+
+var errCNAMELoop = errors.New("CNAME loop detected")
+
+type CNAMEHop struct {
+    Name    string `json:"name"`
+    Target  string `json:"target"`
+    TTL     uint32 `json:"ttl"`
+    Service string `json:"service,omitempty"`
+}
+
+type signatureMatch struct {
+    signature ServiceSignature
+    hop       int
+}
+
+var resolver *resolverPool
+
+func setupResolver() error {
+    resolvers := systemResolvers()
+    if config.ResolversFile != "" {
+        var err error
+        resolvers, err = readResolversFile(config.ResolversFile)
+        if err != nil {
+            return err
+        }
+    }
+
+    pool, err := newResolverPool(resolvers, config.TrustedResolver, config.ResolverRate,
+        config.DNSRetries, time.Duration(config.DNSTimeout)*time.Second)
+    if err != nil {
+        return err
+    }
+
+    resolver = pool
+    return nil
+}
+
+func queryDNS(name string, qtype uint16) (*dns.Msg, error) {
+    return resolver.Query(name, qtype)
+}
+
+func lookupIPs(name string) []net.IP {
+    var ips []net.IP
+    for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+        resp, err := queryDNS(name, qtype)
+        if err != nil {
+            continue
+        }
+        for _, rr := range resp.Answer {
+            switch record := rr.(type) {
+            case *dns.A:
+                ips = append(ips, record.A)
+            case *dns.AAAA:
+                ips = append(ips, record.AAAA)
+            }
+        }
+    }
+    return ips
+}
+
+func resolveCNAMEChain(name string) ([]CNAMEHop, int, error) {
+    var hops []CNAMEHop
+    current := dns.Fqdn(name)
+    visited := map[string]bool{strings.ToLower(current): true}
+
+    for {
+        resp, err := queryDNS(current, dns.TypeCNAME)
+        if err != nil {
+            return hops, 0, err
+        }
+
+        var hop *CNAMEHop
+        for _, rr := range resp.Answer {
+            if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, current) {
+                hop = &CNAMEHop{Name: current, Target: cname.Target, TTL: cname.Hdr.Ttl}
+                break
+            }
+        }
+
+        if hop == nil {
+            break
+        }
+
+        hops = append(hops, *hop)
+        if visited[strings.ToLower(hop.Target)] {
+            return hops, 0, errCNAMELoop
+        }
+        if len(hops) >= maxCNAMEDepth {
+            return hops, 0, fmt.Errorf("CNAME chain longer than %d hops", maxCNAMEDepth)
+        }
+
+        visited[strings.ToLower(hop.Target)] = true
+        current = hop.Target
+    }
+
+    resp, err := queryDNS(current, dns.TypeA)
+    if err != nil {
+        return hops, 0, err
+    }
+
+    return hops, resp.Rcode, nil
+}
+
+func resolveAnswer(name string, answer *resolvedAnswer) ([]CNAMEHop, int, error) {
+    if answer == nil {
+        return resolveCNAMEChain(name)
+    }
+
+    hops := append([]CNAMEHop(nil), answer.hops...)
+    if answer.rcode != rcodeUnknown {
+        return hops, answer.rcode, nil
+    }
+
+    final := dns.Fqdn(name)
+    if len(hops) > 0 {
+        final = hops[len(hops)-1].Target
+    }
+    resp, err := queryDNS(final, dns.TypeA)
+    if err != nil {
+        return hops, 0, err
+    }
+    return hops, resp.Rcode, nil
+}
+
+func targetIPs(target scanTarget) []net.IP {
+    if target.answer != nil && len(target.answer.ips) > 0 {
+        return target.answer.ips
+    }
+    return lookupIPs(target.name)
+}
+
+func formatChain(hops []CNAMEHop) string {
+    if len(hops) == 0 {
+        return ""
+    }
+
+    names := []string{hops[0].Name}
+    for _, hop := range hops {
+        names = append(names, hop.Target)
+    }
+    return strings.Join(names, " -> ")
+}
+
+func matchChainSignatures(subdomain string, hops []CNAMEHop) []signatureMatch {
+    var matches []signatureMatch
+
+    for _, signature := range signatures {
+        if len(hops) == 0 {
+            if matchesCNAME(dns.Fqdn(subdomain), signature.CNAMES) {
+                matches = append(matches, signatureMatch{signature: signature, hop: -1})
+            }
+            continue
+        }
+
+        for i := range hops {
+            if matchesCNAME(hops[i].Target, signature.CNAMES) {
+                if hops[i].Service == "" {
+                    hops[i].Service = signature.Service
+                }
+                matches = append(matches, signatureMatch{signature: signature, hop: i})
+                break
+            }
+        }
+    }
+
+    return matches
+}
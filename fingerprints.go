@@ -0,0 +1,283 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+type xyzFingerprint struct {
+    Service       string   `json:"service"`
+    CNAME         []string `json:"cname"`
+    Fingerprint   string   `json:"fingerprint"`
+    HTTPStatus    *int     `json:"http_status"`
+    NXDomain      bool     `json:"nxdomain"`
+    Status        string   `json:"status"`
+    Vulnerable    bool     `json:"vulnerable"`
+    Discussion    string   `json:"discussion"`
+    Documentation string   `json:"documentation"`
+}
+
+var markdownLinkRegex = regexp.MustCompile(`\]\((https?://[^)\s]+)\)`)
+
+func importFingerprints(filename string) ([]ServiceSignature, []string, error) {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return nil, nil, fmt.Errorf("reading fingerprints file: %v", err)
+    }
+
+    var entries []xyzFingerprint
+    if err := json.Unmarshal(data, &entries); err != nil {
+        return nil, nil, fmt.Errorf("parsing fingerprints file %s: %v", filename, err)
+    }
+
+    var imported []ServiceSignature
+    var skipped []string
+    seen := make(map[string]bool)
+
+    for _, entry := range entries {
+        sig, reason := convertFingerprint(entry)
+        if reason == "" && seen[strings.ToLower(sig.Service)] {
+            reason = "duplicate service"
+        }
+        if reason == "" {
+            if err := validateSignature(sig); err != nil {
+                reason = err.Error()
+            }
+        }
+        if reason != "" {
+            skipped = append(skipped, fmt.Sprintf("%s: %s", entry.Service, reason))
+            continue
+        }
+
+        seen[strings.ToLower(sig.Service)] = true
+        sig.Source = filename
+        imported = append(imported, sig)
+    }
+
+    return imported, skipped, nil
+}
+
+func convertFingerprint(entry xyzFingerprint) (ServiceSignature, string) {
+    sig := ServiceSignature{
+        Service:  strings.TrimSpace(entry.Service),
+        NXDomain: entry.NXDomain,
+    }
+
+    switch strings.ToLower(strings.TrimSpace(entry.Status)) {
+    case "vulnerable":
+        sig.Confidence = "high"
+    case "edge case":
+        sig.Confidence = "medium"
+    case "not vulnerable":
+        return sig, "status is Not vulnerable"
+    default:
+        if !entry.Vulnerable {
+            return sig, fmt.Sprintf("unknown status %q", entry.Status)
+        }
+        sig.Confidence = "medium"
+    }
+
+    for _, cname := range entry.CNAME {
+        cname = strings.TrimSpace(cname)
+        if cname == "" {
+            continue
+        }
+        if !strings.HasPrefix(cname, ".") {
+            cname = "." + cname
+        }
+        sig.CNAMES = append(sig.CNAMES, cname)
+    }
+    if len(sig.CNAMES) == 0 {
+        return sig, "no cname patterns"
+    }
+
+    fingerprint := strings.TrimSpace(entry.Fingerprint)
+    if fingerprint != "" && fingerprint != "NXDOMAIN" {
+        sig.Fingerprint = fingerprint
+        sig.BodyMatch = regexp.QuoteMeta(fingerprint)
+    } else if entry.NXDomain {
+        sig.Fingerprint = "NXDOMAIN"
+    }
+
+    if entry.HTTPStatus != nil {
+        sig.StatusCode = *entry.HTTPStatus
+    }
+
+    for _, text := range []string{entry.Discussion, entry.Documentation} {
+        for _, match := range markdownLinkRegex.FindAllStringSubmatch(text, -1) {
+            sig.References = append(sig.References, match[1])
+        }
+    }
+
+    return sig, ""
+}
+
+func writeImportedSignatures(imported []ServiceSignature, filename string) error {
+    data, err := json.MarshalIndent(signatureFile{Signatures: imported}, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    if filename == "" {
+        fmt.Println(string(data))
+        return nil
+    }
+
+    return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+func diffSignatures(builtin, imported []ServiceSignature) {
+    byName := make(map[string]ServiceSignature)
+    for _, sig := range builtin {
+        byName[normalizeServiceName(sig.Service)] = sig
+    }
+
+    matched := make(map[string]bool)
+    var added, changed []string
+
+    for _, sig := range imported {
+        existing, ok := byName[normalizeServiceName(sig.Service)]
+        if !ok {
+            existing, ok = findByCNAME(builtin, sig.CNAMES)
+        }
+        if !ok {
+            added = append(added, fmt.Sprintf("%s (%s)", sig.Service, strings.Join(sig.CNAMES, ",")))
+            continue
+        }
+
+        matched[existing.Service] = true
+        if changes := signatureChanges(existing, sig); len(changes) > 0 {
+            changed = append(changed, fmt.Sprintf("%s <-> %s: %s", existing.Service, sig.Service, strings.Join(changes, "; ")))
+        }
+    }
+
+    var missing []string
+    for _, sig := range builtin {
+        if !matched[sig.Service] {
+            missing = append(missing, sig.Service)
+        }
+    }
+
+    sort.Strings(added)
+    sort.Strings(changed)
+
+    color.Green("[+] Only in imported set: %d", len(added))
+    for _, line := range added {
+        fmt.Println("    + " + line)
+    }
+    color.Red("[-] Only in built-ins: %d", len(missing))
+    for _, line := range missing {
+        fmt.Println("    - " + line)
+    }
+    color.Yellow("[~] Different definitions: %d", len(changed))
+    for _, line := range changed {
+        fmt.Println("    ~ " + line)
+    }
+}
+
+func signatureChanges(current, incoming ServiceSignature) []string {
+    var changes []string
+
+    currentCNAMES := strings.Join(sortedCopy(current.CNAMES), ",")
+    incomingCNAMES := strings.Join(sortedCopy(incoming.CNAMES), ",")
+    if currentCNAMES != incomingCNAMES {
+        changes = append(changes, fmt.Sprintf("cnames %s -> %s", currentCNAMES, incomingCNAMES))
+    }
+    if current.StatusCode != incoming.StatusCode {
+        changes = append(changes, fmt.Sprintf("status %d -> %d", current.StatusCode, incoming.StatusCode))
+    }
+    if current.NXDomain != incoming.NXDomain {
+        changes = append(changes, fmt.Sprintf("nxdomain %v -> %v", current.NXDomain, incoming.NXDomain))
+    }
+    if current.Confidence != incoming.Confidence {
+        changes = append(changes, fmt.Sprintf("confidence %s -> %s", current.Confidence, incoming.Confidence))
+    }
+    if incoming.Fingerprint != "" && incoming.Fingerprint != "NXDOMAIN" && !regexMatches(current.BodyMatch, incoming.Fingerprint) {
+        changes = append(changes, fmt.Sprintf("fingerprint %q not matched by body_match %q", incoming.Fingerprint, current.BodyMatch))
+    }
+
+    return changes
+}
+
+func findByCNAME(sigs []ServiceSignature, cnames []string) (ServiceSignature, bool) {
+    for _, sig := range sigs {
+        for _, pattern := range sig.CNAMES {
+            for _, cname := range cnames {
+                if strings.EqualFold(pattern, cname) {
+                    return sig, true
+                }
+            }
+        }
+    }
+    return ServiceSignature{}, false
+}
+
+func normalizeServiceName(name string) string {
+    var b strings.Builder
+    for _, r := range strings.ToLower(name) {
+        if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+            b.WriteRune(r)
+        }
+    }
+    return b.String()
+}
+
+func regexMatches(pattern, text string) bool {
+    if pattern == "" {
+        return false
+    }
+    matched, err := regexp.MatchString(pattern, text)
+    return err == nil && matched
+}
+
+func sortedCopy(items []string) []string {
+    sorted := append([]string(nil), items...)
+    sort.Strings(sorted)
+    return sorted
+}
+
+func runFingerprintImport(importFile, diffFile string) {
+    if importFile != "" {
+        imported, skipped, err := importFingerprints(importFile)
+        if err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+
+        for _, line := range skipped {
+            fmt.Fprintln(os.Stderr, yellow("[~] Skipped "+line))
+        }
+
+        if err := writeImportedSignatures(imported, config.OutputFile); err != nil {
+            color.Red("[-] Error writing imported signatures: %v", err)
+            os.Exit(1)
+        }
+        if config.OutputFile != "" {
+            color.Green("[+] Imported %d signatures to: %s", len(imported), config.OutputFile)
+        }
+    }
+
+    if diffFile != "" {
+        imported, _, err := importFingerprints(diffFile)
+        if err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+
+        var builtin []ServiceSignature
+        for _, sig := range signatures {
+            if sig.Source == builtinSource {
+                builtin = append(builtin, sig)
+            }
+        }
+
+        color.Cyan("[+] Comparing %d imported signatures against %d built-ins", len(imported), len(builtin))
+        diffSignatures(builtin, imported)
+    }
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "strings"
+
+    "github.com/fatih/color"
+    "github.com/miekg/dns"
+)
+
+const rcodeUnknown = -1
+
+var inputFormats = map[string]bool{
+    "plain":     true,
+    "amass":     true,
+    "subfinder": true,
+    "massdns":   true,
+    "dnsx":      true,
+}
+
+type resolvedAnswer struct {
+    hops        []CNAMEHop
+    ips         []net.IP
+    rcode       int
+    nameServers []string
+    mx          []string
+}
+
+func (a *resolvedAnswer) hasNameServers() bool {
+    return a != nil && len(a.nameServers) > 0
+}
+
+func (a *resolvedAnswer) nameServersOrNil() []string {
+    if a == nil {
+        return nil
+    }
+    return a.nameServers
+}
+
+func (a *resolvedAnswer) hasMX() bool {
+    return a != nil && len(a.mx) > 0
+}
+
+type amassRecord struct {
+    Name string `json:"name"`
+}
+
+type subfinderRecord struct {
+    Host string `json:"host"`
+}
+
+type dnsxRecord struct {
+    Host       string   `json:"host"`
+    A          []string `json:"a"`
+    AAAA       []string `json:"aaaa"`
+    CNAME      []string `json:"cname"`
+    StatusCode string   `json:"status_code"`
+}
+
+type massdnsRecord struct {
+    Name   string `json:"name"`
+    Status string `json:"status"`
+    Data   struct {
+        Answers []struct {
+            Name string `json:"name"`
+            Type string `json:"type"`
+            Data string `json:"data"`
+            TTL  uint32 `json:"ttl"`
+        } `json:"answers"`
+    } `json:"data"`
+}
+
+type massdnsGroup struct {
+    name  string
+    names map[string]bool
+    cname map[string]CNAMEHop
+    ips   []net.IP
+}
+
+func parseInputLine(format, line string) (scanTarget, error) {
+    switch format {
+    case "amass":
+        var record amassRecord
+        if err := json.Unmarshal([]byte(line), &record); err != nil {
+            return scanTarget{}, err
+        }
+        return scanTarget{name: normalizeTarget(record.Name)}, nil
+
+    case "subfinder":
+        var record subfinderRecord
+        if err := json.Unmarshal([]byte(line), &record); err != nil {
+            return scanTarget{}, err
+        }
+        return scanTarget{name: normalizeTarget(record.Host)}, nil
+
+    case "dnsx":
+        var record dnsxRecord
+        if err := json.Unmarshal([]byte(line), &record); err != nil {
+            return scanTarget{}, err
+        }
+        return dnsxTarget(record), nil
+
+    case "massdns":
+        var record massdnsRecord
+        if err := json.Unmarshal([]byte(line), &record); err != nil {
+            return scanTarget{}, err
+        }
+        return massdnsTarget(record), nil
+    }
+
+    return scanTarget{name: normalizeTarget(line)}, nil
+}
+
+func dnsxTarget(record dnsxRecord) scanTarget {
+    target := scanTarget{name: normalizeTarget(record.Host)}
+
+    rcode, ok := dns.StringToRcode[strings.ToUpper(record.StatusCode)]
+    if !ok || record.CNAME == nil {
+        return target
+    }
+    if len(record.A)+len(record.AAAA)+len(record.CNAME) == 0 && rcode != dns.RcodeNameError {
+        return target
+    }
+
+    answer := &resolvedAnswer{rcode: rcode}
+    if rcode == dns.RcodeSuccess && len(record.A)+len(record.AAAA) == 0 {
+        answer.rcode = rcodeUnknown
+    }
+    current := dns.Fqdn(target.name)
+    for _, cname := range record.CNAME {
+        hop := CNAMEHop{Name: current, Target: dns.Fqdn(strings.ToLower(cname))}
+        answer.hops = append(answer.hops, hop)
+        current = hop.Target
+    }
+    for _, addr := range append(record.A, record.AAAA...) {
+        if ip := net.ParseIP(addr); ip != nil {
+            answer.ips = append(answer.ips, ip)
+        }
+    }
+
+    target.answer = answer
+    return target
+}
+
+func massdnsTarget(record massdnsRecord) scanTarget {
+    target := scanTarget{name: normalizeTarget(record.Name)}
+
+    rcode, ok := dns.StringToRcode[strings.ToUpper(record.Status)]
+    if !ok || rcode == dns.RcodeServerFailure || rcode == dns.RcodeRefused {
+        return target
+    }
+
+    cnames := make(map[string]CNAMEHop)
+    var ips []net.IP
+    for _, answer := range record.Data.Answers {
+        switch strings.ToUpper(answer.Type) {
+        case "CNAME":
+            name := strings.ToLower(dns.Fqdn(answer.Name))
+            cnames[name] = CNAMEHop{Name: name, Target: strings.ToLower(dns.Fqdn(answer.Data)), TTL: answer.TTL}
+        case "A", "AAAA":
+            if ip := net.ParseIP(answer.Data); ip != nil {
+                ips = append(ips, ip)
+            }
+        }
+    }
+
+    target.answer = &resolvedAnswer{hops: followCNAMEs(target.name, cnames), ips: ips, rcode: rcode}
+    return target
+}
+
+func followCNAMEs(name string, cnames map[string]CNAMEHop) []CNAMEHop {
+    var hops []CNAMEHop
+    current := dns.Fqdn(name)
+    for len(hops) < maxCNAMEDepth {
+        hop, ok := cnames[current]
+        if !ok {
+            break
+        }
+        hops = append(hops, hop)
+        current = hop.Target
+    }
+    return hops
+}
+
+func streamMassdnsSimple(r io.Reader, emit func(scanTarget)) error {
+    var group *massdnsGroup
+
+    flush := func() {
+        if group == nil {
+            return
+        }
+        answer := &resolvedAnswer{hops: followCNAMEs(group.name, group.cname), ips: group.ips, rcode: dns.RcodeSuccess}
+        if len(group.ips) == 0 {
+            answer.rcode = rcodeUnknown
+        }
+        emit(scanTarget{name: normalizeTarget(group.name), answer: answer})
+        group = nil
+    }
+
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), maxInputLine)
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 3 {
+            continue
+        }
+        name, rtype, data := strings.ToLower(dns.Fqdn(fields[0])), strings.ToUpper(fields[1]), fields[2]
+
+        if group == nil || !group.names[name] {
+            flush()
+            group = &massdnsGroup{name: name, names: map[string]bool{name: true}, cname: make(map[string]CNAMEHop)}
+        }
+
+        switch rtype {
+        case "CNAME":
+            target := strings.ToLower(dns.Fqdn(data))
+            group.cname[name] = CNAMEHop{Name: name, Target: target}
+            group.names[target] = true
+        case "A", "AAAA":
+            if ip := net.ParseIP(data); ip != nil {
+                group.ips = append(group.ips, ip)
+            }
+        }
+    }
+    flush()
+
+    return scanner.Err()
+}
+
+func readFormattedTargets(r io.Reader, format string, emit func(scanTarget)) error {
+    reader := bufio.NewReader(r)
+
+    if format == "massdns" {
+        peek, _ := reader.Peek(1)
+        if len(peek) > 0 && peek[0] != '{' {
+            return streamMassdnsSimple(reader, emit)
+        }
+    }
+
+    scanner := bufio.NewScanner(reader)
+    scanner.Buffer(make([]byte, 64*1024), maxInputLine)
+    for line := 1; scanner.Scan(); line++ {
+        text := strings.TrimSpace(scanner.Text())
+        if text == "" {
+            continue
+        }
+
+        target, err := parseInputLine(format, text)
+        if err != nil {
+            color.Yellow("[~] Skipping %s input line %d: %v", format, line, err)
+            continue
+        }
+        emit(target)
+    }
+
+    return scanner.Err()
+}
+
+func validateInputFormat(format string) error {
+    if format != "" && !inputFormats[format] && !providerFormats[format] {
+        return fmt.Errorf("unknown input format %q (valid: plain, amass, subfinder, massdns, dnsx, route53, cloudflare, azure, gcloud)", format)
+    }
+    return nil
+}
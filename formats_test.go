@@ -0,0 +1,153 @@
+package main
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/miekg/dns"
+)
+
+func readTargets(t *testing.T, format, input string) []scanTarget {
+    t.Helper()
+
+    var targets []scanTarget
+    if err := readFormattedTargets(strings.NewReader(input), format, func(target scanTarget) {
+        targets = append(targets, target)
+    }); err != nil {
+        t.Fatalf("readFormattedTargets(%s): %v", format, err)
+    }
+    return targets
+}
+
+func hopChain(answer *resolvedAnswer) string {
+    if answer == nil {
+        return ""
+    }
+    var chain []string
+    for _, hop := range answer.hops {
+        chain = append(chain, hop.Name+">"+hop.Target)
+    }
+    return strings.Join(chain, ",")
+}
+
+func ipList(answer *resolvedAnswer) string {
+    if answer == nil {
+        return ""
+    }
+    var ips []string
+    for _, ip := range answer.ips {
+        ips = append(ips, ip.String())
+    }
+    return strings.Join(ips, ",")
+}
+
+type wantTarget struct {
+    name     string
+    resolved bool
+    rcode    int
+    hops     string
+    ips      string
+}
+
+func checkTargets(t *testing.T, got []scanTarget, want []wantTarget) {
+    t.Helper()
+
+    if len(got) != len(want) {
+        t.Fatalf("got %d targets, want %d: %+v", len(got), len(want), got)
+    }
+    for i, w := range want {
+        g := got[i]
+        if g.name != w.name {
+            t.Errorf("target %d: name %q, want %q", i, g.name, w.name)
+        }
+        if (g.answer != nil) != w.resolved {
+            t.Errorf("%s: pre-resolved = %v, want %v", w.name, g.answer != nil, w.resolved)
+            continue
+        }
+        if g.answer == nil {
+            continue
+        }
+        if g.answer.rcode != w.rcode {
+            t.Errorf("%s: rcode %d, want %d", w.name, g.answer.rcode, w.rcode)
+        }
+        if chain := hopChain(g.answer); chain != w.hops {
+            t.Errorf("%s: hops %q, want %q", w.name, chain, w.hops)
+        }
+        if ips := ipList(g.answer); ips != w.ips {
+            t.Errorf("%s: ips %q, want %q", w.name, ips, w.ips)
+        }
+    }
+}
+
+func TestReadDnsxTargets(t *testing.T) {
+    input := `{"host":"www.example.com","a":["192.0.2.1"],"cname":["app.herokuapp.com","us.herokuapp.com"],"status_code":"NOERROR"}
+{"host":"dead.example.com","cname":["gone.azurewebsites.net"],"status_code":"NXDOMAIN"}
+{"host":"api.example.com","a":["192.0.2.2"],"status_code":"NOERROR"}
+{"host":"cdn.example.com","cname":["d111.cloudfront.net"],"status_code":"NOERROR"}
+{"host":"odd.example.com","a":["192.0.2.3"],"cname":[],"status_code":"WEIRD"}
+not json
+`
+
+    checkTargets(t, readTargets(t, "dnsx", input), []wantTarget{
+        {name: "www.example.com", resolved: true, rcode: dns.RcodeSuccess, hops: "www.example.com.>app.herokuapp.com.,app.herokuapp.com.>us.herokuapp.com.", ips: "192.0.2.1"},
+        {name: "dead.example.com", resolved: true, rcode: dns.RcodeNameError, hops: "dead.example.com.>gone.azurewebsites.net."},
+        {name: "api.example.com"},
+        {name: "cdn.example.com", resolved: true, rcode: rcodeUnknown, hops: "cdn.example.com.>d111.cloudfront.net."},
+        {name: "odd.example.com"},
+    })
+}
+
+func TestReadMassdnsSimpleTargets(t *testing.T) {
+    input := `www.example.com. CNAME app.herokuapp.com.
+app.herokuapp.com. CNAME us.herokuapp.com.
+us.herokuapp.com. A 192.0.2.1
+cdn.example.com. CNAME d111.cloudfront.net.
+api.example.com. A 192.0.2.2
+api.example.com. AAAA 2001:db8::1
+`
+
+    checkTargets(t, readTargets(t, "massdns", input), []wantTarget{
+        {name: "www.example.com", resolved: true, rcode: dns.RcodeSuccess, hops: "www.example.com.>app.herokuapp.com.,app.herokuapp.com.>us.herokuapp.com.", ips: "192.0.2.1"},
+        {name: "cdn.example.com", resolved: true, rcode: rcodeUnknown, hops: "cdn.example.com.>d111.cloudfront.net."},
+        {name: "api.example.com", resolved: true, rcode: dns.RcodeSuccess, ips: "192.0.2.2,2001:db8::1"},
+    })
+}
+
+func TestReadMassdnsJSONTargets(t *testing.T) {
+    input := `{"name":"www.example.com.","type":"A","status":"NOERROR","data":{"answers":[{"ttl":300,"type":"CNAME","name":"www.example.com.","data":"app.herokuapp.com."},{"ttl":60,"type":"A","name":"app.herokuapp.com.","data":"192.0.2.1"}]}}
+{"name":"dead.example.com.","type":"A","status":"NXDOMAIN","data":{}}
+{"name":"flaky.example.com.","type":"A","status":"SERVFAIL","data":{}}
+`
+
+    targets := readTargets(t, "massdns", input)
+    checkTargets(t, targets, []wantTarget{
+        {name: "www.example.com", resolved: true, rcode: dns.RcodeSuccess, hops: "www.example.com.>app.herokuapp.com.", ips: "192.0.2.1"},
+        {name: "dead.example.com", resolved: true, rcode: dns.RcodeNameError},
+        {name: "flaky.example.com"},
+    })
+    if ttl := targets[0].answer.hops[0].TTL; ttl != 300 {
+        t.Errorf("CNAME ttl = %d, want 300", ttl)
+    }
+}
+
+func TestReadNameOnlyTargets(t *testing.T) {
+    tests := []struct {
+        format string
+        input  string
+    }{
+        {"amass", `{"name":"WWW.example.com","domain":"example.com","addresses":[{"ip":"192.0.2.1"}],"tag":"dns","sources":["DNS"]}
+{"name":"api.example.com.","domain":"example.com"}
+{broken
+`},
+        {"subfinder", `{"host":"www.example.com","input":"example.com","source":"crtsh"}
+{"host":"*.api.example.com","input":"example.com","source":"alienvault"}
+`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.format, func(t *testing.T) {
+            want := []wantTarget{{name: "www.example.com"}, {name: "api.example.com"}}
+            checkTargets(t, readTargets(t, tt.format, tt.input), want)
+        })
+    }
+}
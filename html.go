@@ -0,0 +1,257 @@
+package main
+
+import (
+    "bufio"
+    "html/template"
+    "os"
+    "sort"
+    "strings"
+    "time"
+)
+
+type htmlWriter struct {
+    file     *os.File
+    stats    scanStats
+    findings []Result
+}
+
+type htmlReport struct {
+    Generated  string
+    Total      int
+    Vulnerable int
+    Potential  int
+    Wildcard   int
+    Safe       int
+    Findings   []Result
+    Services   []string
+}
+
+var htmlFuncs = template.FuncMap{
+    "statusLabel": func(status string) string {
+        switch status {
+        case "vulnerable":
+            return "Vulnerable"
+        case "potentially_vulnerable":
+            return "Potential"
+        case "wildcard":
+            return "Wildcard"
+        }
+        return "Safe"
+    },
+    "statusRank":     statusRank,
+    "confidenceRank": confidenceRank,
+    "target":         resultTarget,
+    "join":           strings.Join,
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>SubTake report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; background: #fafafa; }
+h1 { margin-bottom: 0; }
+.generated { color: #777; margin-top: .3em; }
+.counters { display: flex; gap: 1em; margin: 1.5em 0; flex-wrap: wrap; }
+.counter { background: #fff; border: 1px solid #ddd; border-radius: 6px; padding: .8em 1.4em; min-width: 7em; }
+.counter b { display: block; font-size: 1.8em; }
+.vulnerable b, tr.vulnerable .status { color: #c0392b; }
+.potentially_vulnerable b, tr.potentially_vulnerable .status { color: #d68910; }
+.wildcard b, tr.wildcard .status { color: #2471a3; }
+.safe b { color: #1e8449; }
+.filters { display: flex; gap: .8em; margin-bottom: 1em; flex-wrap: wrap; }
+.filters select, .filters input { padding: .3em; }
+table { border-collapse: collapse; width: 100%; background: #fff; }
+th, td { border: 1px solid #ddd; padding: .45em .6em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; white-space: nowrap; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+.status { font-weight: bold; }
+details summary { cursor: pointer; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .2em 1em; margin: .6em 0 0; font-size: .9em; }
+dt { color: #777; }
+dd { margin: 0; word-break: break-all; }
+code { background: #f4f4f4; padding: 0 .2em; }
+.empty { color: #777; }
+</style>
+</head>
+<body>
+<h1>SubTake report</h1>
+<p class="generated">Generated {{.Generated}}</p>
+
+<div class="counters">
+<div class="counter"><b>{{.Total}}</b>Total targets</div>
+<div class="counter vulnerable"><b>{{.Vulnerable}}</b>Vulnerable</div>
+<div class="counter potentially_vulnerable"><b>{{.Potential}}</b>Potential</div>
+<div class="counter wildcard"><b>{{.Wildcard}}</b>Wildcard</div>
+<div class="counter safe"><b>{{.Safe}}</b>Safe</div>
+</div>
+
+{{if .Findings}}
+<div class="filters">
+<select id="status">
+<option value="">All statuses</option>
+<option value="vulnerable">Vulnerable</option>
+<option value="potentially_vulnerable">Potential</option>
+<option value="wildcard">Wildcard</option>
+</select>
+<select id="service">
+<option value="">All services</option>
+{{range .Services}}<option>{{.}}</option>
+{{end}}</select>
+<select id="confidence">
+<option value="">All confidences</option>
+<option>high</option>
+<option>medium</option>
+<option>low</option>
+</select>
+<input id="search" type="search" placeholder="Filter subdomains">
+</div>
+
+<table id="findings">
+<thead>
+<tr><th data-type="number">Status</th><th>Subdomain</th><th>Target</th><th>Service</th><th data-type="number">Confidence</th><th>Evidence</th></tr>
+</thead>
+<tbody>
+{{range .Findings}}<tr class="{{.Status}}" data-status="{{.Status}}" data-service="{{.Service}}" data-confidence="{{.Confidence}}">
+<td class="status" data-sort="{{statusRank .Status}}">{{statusLabel .Status}}</td>
+<td data-sort="{{.Subdomain}}"><details><summary>{{.Subdomain}}</summary><dl>
+{{- if .RecordType}}<dt>Record</dt><dd>{{.RecordType}}</dd>{{end}}
+{{- range .CNAMEChain}}<dt>CNAME</dt><dd>{{.Name}} &rarr; {{.Target}}{{if .Service}} ({{.Service}}){{end}}</dd>{{end}}
+{{- if .IP}}<dt>IP</dt><dd>{{.IP}}</dd>{{end}}
+{{- if .NameServers}}<dt>Name servers</dt><dd>{{join .NameServers ", "}}</dd>{{end}}
+{{- range .DanglingRecords}}<dt>{{.Type}}</dt><dd>{{.Value}}{{if .Reason}} ({{.Reason}}){{end}}</dd>{{end}}
+{{- if .HTTPStatus}}<dt>HTTP status</dt><dd>{{.HTTPStatus}}</dd>{{end}}
+{{- if .Snippet}}<dt>Matched</dt><dd><code>{{.Snippet}}</code></dd>{{end}}
+{{- if .ResponseTime}}<dt>Response time</dt><dd>{{.ResponseTime}} ms</dd>{{end}}
+{{- if .CloudProvider}}<dt>Cloud</dt><dd>{{.CloudProvider}} {{.CloudRegion}}</dd>{{end}}
+{{- if .ZoneID}}<dt>Zone</dt><dd>{{.ZoneID}}</dd>{{end}}
+{{- if .Resource}}<dt>Resource</dt><dd>{{.Resource}}</dd>{{end}}
+</dl></details></td>
+<td>{{target .}}</td>
+<td>{{.Service}}</td>
+<td data-sort="{{confidenceRank .Confidence}}">{{.Confidence}}</td>
+<td>{{.Evidence}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+
+<script>
+(function () {
+    var table = document.getElementById("findings");
+    var body = table.tBodies[0];
+    var filters = ["status", "service", "confidence"].map(function (id) { return document.getElementById(id); });
+    var search = document.getElementById("search");
+
+    function apply() {
+        var text = search.value.toLowerCase();
+        Array.prototype.forEach.call(body.rows, function (row) {
+            var show = filters.every(function (f) { return !f.value || row.dataset[f.id] === f.value; });
+            show = show && row.cells[1].dataset.sort.indexOf(text) !== -1;
+            row.style.display = show ? "" : "none";
+        });
+    }
+
+    filters.concat([search]).forEach(function (f) { f.addEventListener("input", apply); });
+
+    Array.prototype.forEach.call(table.tHead.rows[0].cells, function (th, i) {
+        th.addEventListener("click", function () {
+            var desc = !th.classList.contains("desc");
+            Array.prototype.forEach.call(th.parentNode.cells, function (c) { c.classList.remove("asc", "desc"); });
+            th.classList.add(desc ? "desc" : "asc");
+
+            var number = th.dataset.type === "number";
+            var rows = Array.prototype.slice.call(body.rows);
+            rows.sort(function (a, b) {
+                var x = a.cells[i].dataset.sort || a.cells[i].textContent;
+                var y = b.cells[i].dataset.sort || b.cells[i].textContent;
+                var cmp = number ? x - y : x.localeCompare(y);
+                return desc ? -cmp : cmp;
+            });
+            rows.forEach(function (row) { body.appendChild(row); });
+        });
+    });
+})();
+</script>
+{{else}}
+<p class="empty">No findings.</p>
+{{end}}
+</body>
+</html>
+`))
+
+func (h *htmlWriter) Write(result Result) error {
+    h.stats.total++
+    switch result.Status {
+    case "vulnerable":
+        h.stats.vulnerable++
+    case "potentially_vulnerable":
+        h.stats.potential++
+    case "wildcard":
+        h.stats.wildcard++
+    default:
+        return nil
+    }
+
+    h.findings = append(h.findings, result)
+    return nil
+}
+
+func (h *htmlWriter) Flush() error {
+    return nil
+}
+
+func (h *htmlWriter) Close() error {
+    sort.SliceStable(h.findings, func(i, j int) bool {
+        a, b := h.findings[i], h.findings[j]
+        if statusRank(a.Status) != statusRank(b.Status) {
+            return statusRank(a.Status) > statusRank(b.Status)
+        }
+        if confidenceRank(a.Confidence) != confidenceRank(b.Confidence) {
+            return confidenceRank(a.Confidence) > confidenceRank(b.Confidence)
+        }
+        return a.Subdomain < b.Subdomain
+    })
+
+    seen := make(map[string]bool)
+    var services []string
+    for _, result := range h.findings {
+        if result.Service != "" && !seen[result.Service] {
+            seen[result.Service] = true
+            services = append(services, result.Service)
+        }
+    }
+    sort.Strings(services)
+
+    report := htmlReport{
+        Generated:  time.Now().Format("2006-01-02 15:04:05 MST"),
+        Total:      h.stats.total,
+        Vulnerable: h.stats.vulnerable,
+        Potential:  h.stats.potential,
+        Wildcard:   h.stats.wildcard,
+        Safe:       h.stats.total - h.stats.vulnerable - h.stats.potential - h.stats.wildcard,
+        Findings:   h.findings,
+        Services:   services,
+    }
+
+    buffered := bufio.NewWriter(h.file)
+    if err := htmlTemplate.Execute(buffered, report); err != nil {
+        h.file.Close()
+        return err
+    }
+    return closeBuffered(buffered, h.file)
+}
+
+func confidenceRank(confidence string) int {
+    switch confidence {
+    case "high":
+        return 3
+    case "medium":
+        return 2
+    case "low":
+        return 1
+    }
+    return 0
+}
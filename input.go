@@ -0,0 +1,176 @@
+package main
+
+import (
+    "io"
+    "io/fs"
+    "net"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+const (
+    maxInputLine   = 1024 * 1024
+    maxSeenTargets = 1000000
+)
+
+var targetReadErr error
+
+type scanTarget struct {
+    name   string
+    answer *resolvedAnswer
+    zoneID string
+    kube   *kubeHost
+}
+
+func stdinPiped() bool {
+    info, err := os.Stdin.Stat()
+    if err != nil {
+        return false
+    }
+    return info.Mode()&os.ModeCharDevice == 0
+}
+
+func openTargets(filename string) (io.ReadCloser, error) {
+    if filename == "-" {
+        return io.NopCloser(os.Stdin), nil
+    }
+    return os.Open(filename)
+}
+
+func collectFiles(path string, exts ...string) ([]string, error) {
+    info, err := os.Stat(path)
+    if err != nil {
+        return nil, err
+    }
+
+    if !info.IsDir() {
+        return []string{path}, nil
+    }
+
+    var files []string
+    err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+        if d.IsDir() {
+            return nil
+        }
+        ext := strings.ToLower(filepath.Ext(p))
+        for _, want := range exts {
+            if ext == want {
+                files = append(files, p)
+                break
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    sort.Strings(files)
+    return files, nil
+}
+
+func streamTargets(r io.Reader, format string) <-chan scanTarget {
+    targets := make(chan scanTarget, config.Threads)
+
+    go func() {
+        defer close(targets)
+
+        seen := make(map[string]bool)
+        err := readFormattedTargets(r, format, func(target scanTarget) {
+            if target.name == "" || seen[target.name] {
+                return
+            }
+            if len(seen) < maxSeenTargets {
+                seen[target.name] = true
+            } else if len(seen) == maxSeenTargets {
+                seen[target.name] = true
+                color.Yellow("[~] More than %d unique targets, later duplicates are no longer skipped", maxSeenTargets)
+            }
+            targets <- target
+        })
+
+        if err != nil {
+            color.Red("[-] Error reading targets: %v", err)
+            targetReadErr = err
+        }
+    }()
+
+    return targets
+}
+
+func sliceTargets(list []scanTarget) <-chan scanTarget {
+    targets := make(chan scanTarget, config.Threads)
+
+    go func() {
+        defer close(targets)
+        for _, target := range list {
+            targets <- target
+        }
+    }()
+
+    return targets
+}
+
+func normalizeTarget(line string) string {
+    line = strings.TrimSpace(line)
+    if i := strings.Index(line, "#"); i >= 0 {
+        line = line[:i]
+    }
+
+    fields := strings.Fields(line)
+    if len(fields) == 0 {
+        return ""
+    }
+    target := fields[0]
+
+    if i := strings.Index(target, "://"); i >= 0 {
+        target = target[i+3:]
+    }
+    if i := strings.IndexAny(target, "/?"); i >= 0 {
+        target = target[:i]
+    }
+    if i := strings.LastIndex(target, "@"); i >= 0 {
+        target = target[i+1:]
+    }
+    if net.ParseIP(strings.Trim(target, "[]")) != nil {
+        return ""
+    }
+    if i := strings.LastIndex(target, ":"); i >= 0 && isPort(target[i+1:]) {
+        target = target[:i]
+    }
+    if net.ParseIP(strings.Trim(target, "[]")) != nil {
+        return ""
+    }
+
+    target = strings.ToLower(strings.TrimRight(target, "."))
+    for strings.HasPrefix(target, "*.") {
+        target = target[2:]
+    }
+    target = strings.TrimLeft(target, ".")
+
+    for _, c := range target {
+        if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+            return ""
+        }
+    }
+    return target
+}
+
+func isPort(s string) bool {
+    if s == "" {
+        return false
+    }
+    for _, c := range s {
+        if c < '0' || c > '9' {
+            return false
+        }
+    }
+    return true
+}
@@ -11,7 +11,7 @@ echo "[+] Installing dependencies..."
 go mod tidy
 
 echo "[+] Building SubTake..."
-go build -o subtake subtake.go
+go build -o subtake .
 
 chmod +x subtake
 
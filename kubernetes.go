@@ -0,0 +1,254 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+
+    "github.com/miekg/dns"
+    "gopkg.in/yaml.v3"
+)
+
+const (
+    externalDNSHostname = "external-dns.alpha.kubernetes.io/hostname"
+    externalDNSTarget   = "external-dns.alpha.kubernetes.io/target"
+)
+
+type kubeHost struct {
+    source   string
+    backends []string
+}
+
+type kubeObject struct {
+    Kind     string `yaml:"kind"`
+    Metadata struct {
+        Name        string            `yaml:"name"`
+        Namespace   string            `yaml:"namespace"`
+        Annotations map[string]string `yaml:"annotations"`
+    } `yaml:"metadata"`
+    Spec struct {
+        Type  string `yaml:"type"`
+        Rules []struct {
+            Host string `yaml:"host"`
+        } `yaml:"rules"`
+        TLS []struct {
+            Hosts []string `yaml:"hosts"`
+        } `yaml:"tls"`
+        Endpoints []struct {
+            DNSName    string   `yaml:"dnsName"`
+            RecordType string   `yaml:"recordType"`
+            Targets    []string `yaml:"targets"`
+        } `yaml:"endpoints"`
+    } `yaml:"spec"`
+    Status struct {
+        LoadBalancer struct {
+            Ingress []struct {
+                Hostname string `yaml:"hostname"`
+                IP       string `yaml:"ip"`
+            } `yaml:"ingress"`
+        } `yaml:"loadBalancer"`
+    } `yaml:"status"`
+    Items []kubeObject `yaml:"items"`
+}
+
+var kubeBackends = make(map[string]bool)
+
+func readKubernetesTargets(path string) ([]scanTarget, error) {
+    var objects []kubeObject
+
+    files := []string{path}
+    if path != "-" {
+        var err error
+        if files, err = collectFiles(path, ".json", ".yaml", ".yml"); err != nil {
+            return nil, fmt.Errorf("kubernetes manifests %s: %v", path, err)
+        }
+    }
+
+    for _, file := range files {
+        input, err := openTargets(file)
+        if err != nil {
+            return nil, err
+        }
+        data, err := io.ReadAll(input)
+        input.Close()
+        if err != nil {
+            return nil, fmt.Errorf("reading %s: %v", file, err)
+        }
+
+        parsed, err := parseKubeObjects(data)
+        if err != nil {
+            return nil, fmt.Errorf("parsing %s: %v", file, err)
+        }
+        objects = append(objects, parsed...)
+    }
+
+    return kubeTargets(objects), nil
+}
+
+func parseKubeObjects(data []byte) ([]kubeObject, error) {
+    var objects []kubeObject
+
+    decoder := yaml.NewDecoder(bytes.NewReader(data))
+    for {
+        var object kubeObject
+        err := decoder.Decode(&object)
+        if errors.Is(err, io.EOF) {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        objects = append(objects, flattenKubeObjects(object)...)
+    }
+
+    return objects, nil
+}
+
+func flattenKubeObjects(object kubeObject) []kubeObject {
+    if len(object.Items) == 0 {
+        return []kubeObject{object}
+    }
+
+    var objects []kubeObject
+    for _, item := range object.Items {
+        objects = append(objects, flattenKubeObjects(item)...)
+    }
+    return objects
+}
+
+func kubeTargets(objects []kubeObject) []scanTarget {
+    hosts := make(map[string]*kubeHost)
+    var order []string
+
+    add := func(host, source string, backends []string) {
+        host = normalizeTarget(host)
+        if host == "" {
+            return
+        }
+        h, ok := hosts[host]
+        if !ok {
+            h = &kubeHost{source: source}
+            hosts[host] = h
+            order = append(order, host)
+        }
+        for _, backend := range backends {
+            if !containsString(h.backends, backend) {
+                h.backends = append(h.backends, backend)
+            }
+        }
+    }
+
+    for _, object := range objects {
+        source := strings.ToLower(object.Kind) + "/" + object.Metadata.Name
+        if object.Metadata.Namespace != "" {
+            source = strings.ToLower(object.Kind) + "/" + object.Metadata.Namespace + "/" + object.Metadata.Name
+        }
+
+        var backends []string
+        for _, lb := range object.Status.LoadBalancer.Ingress {
+            for _, backend := range []string{lb.Hostname, lb.IP} {
+                if backend != "" {
+                    backends = append(backends, normalizeHost(backend))
+                }
+            }
+        }
+        for _, target := range splitList(object.Metadata.Annotations[externalDNSTarget]) {
+            backends = append(backends, normalizeHost(target))
+        }
+        for _, backend := range backends {
+            kubeBackends[backend] = true
+        }
+
+        var names []string
+        switch object.Kind {
+        case "Ingress":
+            for _, rule := range object.Spec.Rules {
+                names = append(names, rule.Host)
+            }
+            for _, tls := range object.Spec.TLS {
+                names = append(names, tls.Hosts...)
+            }
+        case "Service":
+            if object.Spec.Type != "LoadBalancer" {
+                continue
+            }
+        case "DNSEndpoint":
+            for _, endpoint := range object.Spec.Endpoints {
+                var targets []string
+                for _, target := range endpoint.Targets {
+                    targets = append(targets, normalizeHost(target))
+                }
+                add(endpoint.DNSName, source, targets)
+            }
+            continue
+        default:
+            continue
+        }
+        names = append(names, splitList(object.Metadata.Annotations[externalDNSHostname])...)
+
+        for _, name := range names {
+            add(name, source, backends)
+        }
+    }
+
+    var targets []scanTarget
+    for _, host := range order {
+        targets = append(targets, scanTarget{name: host, kube: hosts[host]})
+    }
+    return targets
+}
+
+func checkKubernetesBackend(target scanTarget, result *Result) {
+    host := target.kube
+    result.Resource = host.source
+
+    if result.Status != "safe" || len(kubeBackends) == 0 {
+        return
+    }
+
+    var pointsAt string
+    switch {
+    case len(result.CNAMEChain) > 0:
+        for _, hop := range result.CNAMEChain {
+            if kubeBackends[normalizeHost(hop.Target)] {
+                return
+            }
+        }
+        pointsAt = normalizeHost(result.CNAMEChain[0].Target)
+        result.RecordType = "CNAME"
+    case result.IP != "":
+        for _, ip := range targetIPs(target) {
+            if kubeBackends[ip.String()] {
+                return
+            }
+        }
+        pointsAt = result.IP
+        result.RecordType = "A"
+        if strings.Contains(result.IP, ":") {
+            result.RecordType = "AAAA"
+        }
+    default:
+        return
+    }
+
+    result.Status = "potentially_vulnerable"
+    result.Service = "Kubernetes LoadBalancer"
+    result.Confidence = "medium"
+    expected := "none"
+    if len(host.backends) > 0 {
+        expected = strings.Join(host.backends, ", ")
+    }
+    result.Evidence = fmt.Sprintf("%s points at %s, which is not a LoadBalancer in the dump (%s has: %s)",
+        dns.Fqdn(target.name), pointsAt, host.source, expected)
+}
+
+func containsString(list []string, value string) bool {
+    for _, item := range list {
+        if item == value {
+            return true
+        }
+    }
+    return false
+}
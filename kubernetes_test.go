@@ -0,0 +1,223 @@
+package main
+
+import (
+    "net"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+const kubectlListFixture = `{
+    "apiVersion": "v1",
+    "kind": "List",
+    "items": [
+        {
+            "apiVersion": "networking.k8s.io/v1",
+            "kind": "Ingress",
+            "metadata": {"name": "shop", "namespace": "web"},
+            "spec": {
+                "rules": [{"host": "shop.example.com"}, {"host": "*.shop.example.com"}],
+                "tls": [{"hosts": ["shop.example.com", "secure.example.com"]}]
+            },
+            "status": {"loadBalancer": {"ingress": [{"hostname": "abc-123.elb.us-east-1.amazonaws.com"}]}}
+        },
+        {
+            "apiVersion": "v1",
+            "kind": "Service",
+            "metadata": {
+                "name": "api",
+                "namespace": "web",
+                "annotations": {"external-dns.alpha.kubernetes.io/hostname": "api.example.com, api2.example.com"}
+            },
+            "spec": {"type": "LoadBalancer"},
+            "status": {"loadBalancer": {"ingress": [{"ip": "192.0.2.10"}]}}
+        },
+        {
+            "apiVersion": "v1",
+            "kind": "Service",
+            "metadata": {
+                "name": "internal",
+                "annotations": {"external-dns.alpha.kubernetes.io/hostname": "internal.example.com"}
+            },
+            "spec": {"type": "ClusterIP"}
+        }
+    ]
+}`
+
+const multiDocFixture = `apiVersion: externaldns.k8s.io/v1alpha1
+kind: DNSEndpoint
+metadata:
+  name: legacy
+  namespace: ops
+spec:
+  endpoints:
+    - dnsName: legacy.example.com
+      recordType: CNAME
+      targets:
+        - old-lb.us-west-2.elb.amazonaws.com.
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: blog
+  annotations:
+    external-dns.alpha.kubernetes.io/target: blog-lb.example.net
+spec:
+  rules:
+    - host: Blog.Example.com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  host: ignored.example.com
+`
+
+func resetKubeBackends(t *testing.T) {
+    t.Helper()
+
+    kubeBackends = make(map[string]bool)
+    t.Cleanup(func() { kubeBackends = make(map[string]bool) })
+}
+
+func TestReadKubernetesTargets(t *testing.T) {
+    resetKubeBackends(t)
+
+    dir := t.TempDir()
+    if err := os.WriteFile(filepath.Join(dir, "dump.json"), []byte(kubectlListFixture), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.MkdirAll(filepath.Join(dir, "manifests"), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(filepath.Join(dir, "manifests", "dns.yml"), []byte(multiDocFixture), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("host: readme.example.com\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    targets, err := readKubernetesTargets(dir)
+    if err != nil {
+        t.Fatalf("readKubernetesTargets: %v", err)
+    }
+
+    want := map[string]struct {
+        source   string
+        backends string
+    }{
+        "shop.example.com":   {"ingress/web/shop", "abc-123.elb.us-east-1.amazonaws.com"},
+        "secure.example.com": {"ingress/web/shop", "abc-123.elb.us-east-1.amazonaws.com"},
+        "api.example.com":    {"service/web/api", "192.0.2.10"},
+        "api2.example.com":   {"service/web/api", "192.0.2.10"},
+        "legacy.example.com": {"dnsendpoint/ops/legacy", "old-lb.us-west-2.elb.amazonaws.com"},
+        "blog.example.com":   {"ingress/blog", "blog-lb.example.net"},
+    }
+
+    if len(targets) != len(want) {
+        var names []string
+        for _, target := range targets {
+            names = append(names, target.name)
+        }
+        t.Errorf("got targets %v, want %d", names, len(want))
+    }
+    for _, target := range targets {
+        w, ok := want[target.name]
+        if !ok {
+            t.Errorf("unexpected target %s", target.name)
+            continue
+        }
+        if target.kube == nil {
+            t.Errorf("%s: no kubernetes host attached", target.name)
+            continue
+        }
+        if target.kube.source != w.source {
+            t.Errorf("%s: source %q, want %q", target.name, target.kube.source, w.source)
+        }
+        if backends := strings.Join(target.kube.backends, ","); backends != w.backends {
+            t.Errorf("%s: backends %q, want %q", target.name, backends, w.backends)
+        }
+    }
+
+    for _, backend := range []string{"abc-123.elb.us-east-1.amazonaws.com", "192.0.2.10", "blog-lb.example.net"} {
+        if !kubeBackends[backend] {
+            t.Errorf("backend %s was not recorded", backend)
+        }
+    }
+}
+
+func TestParseKubeObjectsRejectsBrokenYAML(t *testing.T) {
+    if _, err := parseKubeObjects([]byte("kind: Ingress\nmetadata: [unterminated\n")); err == nil {
+        t.Error("broken YAML was accepted")
+    }
+}
+
+func TestCheckKubernetesBackend(t *testing.T) {
+    resetKubeBackends(t)
+    kubeBackends["abc-123.elb.us-east-1.amazonaws.com"] = true
+    kubeBackends["192.0.2.10"] = true
+
+    host := &kubeHost{source: "ingress/web/shop", backends: []string{"abc-123.elb.us-east-1.amazonaws.com"}}
+
+    tests := []struct {
+        name       string
+        result     Result
+        ips        []string
+        vulnerable bool
+        recordType string
+    }{
+        {
+            name:   "cname to a live backend",
+            result: Result{Status: "safe", CNAMEChain: []CNAMEHop{{Name: "shop.example.com.", Target: "abc-123.elb.us-east-1.amazonaws.com."}}},
+        },
+        {
+            name:       "cname to a backend that is gone",
+            result:     Result{Status: "safe", CNAMEChain: []CNAMEHop{{Name: "shop.example.com.", Target: "old-456.elb.us-east-1.amazonaws.com."}}},
+            vulnerable: true,
+            recordType: "CNAME",
+        },
+        {
+            name:   "address of a live backend",
+            result: Result{Status: "safe", IP: "192.0.2.10"},
+            ips:    []string{"192.0.2.10"},
+        },
+        {
+            name:       "ipv6 address outside the dump",
+            result:     Result{Status: "safe", IP: "2001:db8::1"},
+            ips:        []string{"2001:db8::1"},
+            vulnerable: true,
+            recordType: "AAAA",
+        },
+        {
+            name:   "already vulnerable results are left alone",
+            result: Result{Status: "vulnerable", Service: "Heroku", CNAMEChain: []CNAMEHop{{Target: "gone.herokuapp.com."}}},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            target := scanTarget{name: "shop.example.com", kube: host}
+            if len(tt.ips) > 0 {
+                target.answer = &resolvedAnswer{rcode: rcodeUnknown}
+                for _, ip := range tt.ips {
+                    target.answer.ips = append(target.answer.ips, net.ParseIP(ip))
+                }
+            }
+
+            result := tt.result
+            checkKubernetesBackend(target, &result)
+
+            if result.Resource != host.source {
+                t.Errorf("resource = %q, want %q", result.Resource, host.source)
+            }
+            if got := result.Service == "Kubernetes LoadBalancer"; got != tt.vulnerable {
+                t.Errorf("flagged = %v, want %v (%+v)", got, tt.vulnerable, result)
+            }
+            if tt.vulnerable && result.RecordType != tt.recordType {
+                t.Errorf("record type = %q, want %q", result.RecordType, tt.recordType)
+            }
+        })
+    }
+}
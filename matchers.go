@@ -0,0 +1,429 @@
+package main
+
+import (
+    "encoding/base64"
+    "fmt"
+    "regexp"
+    "strings"
+
+    "github.com/valyala/fasthttp"
+)
+
+type Matcher struct {
+    Type            string   `json:"type"`
+    Part            string   `json:"part,omitempty"`
+    Words           []string `json:"words,omitempty"`
+    Regex           []string `json:"regex,omitempty"`
+    Status          []int    `json:"status,omitempty"`
+    Size            []int    `json:"size,omitempty"`
+    Hash            []int32  `json:"hash,omitempty"`
+    Condition       string   `json:"condition,omitempty"`
+    Negative        bool     `json:"negative,omitempty"`
+    CaseInsensitive bool     `json:"case_insensitive,omitempty"`
+    Name            string   `json:"name,omitempty"`
+
+    regexes []*regexp.Regexp
+}
+
+type httpResponse struct {
+    status   int
+    headers  []string
+    body     string
+    location string
+    favicon  *int32
+}
+
+const snippetContext = 80
+
+var matcherTypes = map[string]bool{
+    "status":      true,
+    "word":        true,
+    "regex":       true,
+    "header":      true,
+    "body-length": true,
+    "favicon":     true,
+    "redirect":    true,
+}
+
+func compileSignature(sig *ServiceSignature) error {
+    matchers := sig.Matchers
+    if len(matchers) == 0 {
+        matchers = legacyMatchers(*sig)
+    }
+
+    switch strings.ToLower(sig.MatchersCondition) {
+    case "", "and", "or":
+    default:
+        return fmt.Errorf("matchers_condition must be and or or (got %q)", sig.MatchersCondition)
+    }
+
+    compiled := make([]Matcher, len(matchers))
+    for i, m := range matchers {
+        if err := compileMatcher(&m); err != nil {
+            return fmt.Errorf("matcher #%d (%s): %v", i+1, m.Type, err)
+        }
+        compiled[i] = m
+    }
+
+    sig.compiled = compiled
+    sig.exclude = nil
+    if sig.BodyExclude != "" {
+        exclude := Matcher{Type: "regex", Regex: []string{sig.BodyExclude}, Name: "Body exclude"}
+        if err := compileMatcher(&exclude); err != nil {
+            return fmt.Errorf("body_exclude: %v", err)
+        }
+        sig.exclude = &exclude
+    }
+    return nil
+}
+
+func compileSignatures() error {
+    for i := range signatures {
+        if err := compileSignature(&signatures[i]); err != nil {
+            return fmt.Errorf("service %q: %v", signatures[i].Service, err)
+        }
+    }
+    return nil
+}
+
+func compileMatcher(m *Matcher) error {
+    if !matcherTypes[m.Type] {
+        return fmt.Errorf("unknown matcher type %q", m.Type)
+    }
+
+    switch strings.ToLower(m.Condition) {
+    case "", "and", "or":
+    default:
+        return fmt.Errorf("condition must be and or or (got %q)", m.Condition)
+    }
+
+    switch m.Part {
+    case "", "body", "header", "all":
+    default:
+        return fmt.Errorf("unknown part %q", m.Part)
+    }
+
+    switch m.Type {
+    case "status":
+        if len(m.Status) == 0 {
+            return fmt.Errorf("status matcher needs at least one status code")
+        }
+        for _, code := range m.Status {
+            if code < 100 || code > 599 {
+                return fmt.Errorf("invalid status code %d", code)
+            }
+        }
+    case "word", "header":
+        if len(m.Words) == 0 && !(m.Type == "header" && len(m.Regex) > 0) {
+            return fmt.Errorf("%s matcher needs at least one word", m.Type)
+        }
+    case "regex":
+        if len(m.Regex) == 0 {
+            return fmt.Errorf("regex matcher needs at least one pattern")
+        }
+    case "body-length":
+        if len(m.Size) == 0 {
+            return fmt.Errorf("body-length matcher needs at least one size")
+        }
+    case "favicon":
+        if len(m.Hash) == 0 {
+            return fmt.Errorf("favicon matcher needs at least one hash")
+        }
+    case "redirect":
+        if len(m.Words) == 0 && len(m.Regex) == 0 {
+            return fmt.Errorf("redirect matcher needs words or regex")
+        }
+    }
+
+    m.regexes = nil
+    for _, pattern := range m.Regex {
+        if m.CaseInsensitive {
+            pattern = "(?i)" + pattern
+        }
+        re, err := regexp.Compile(pattern)
+        if err != nil {
+            return fmt.Errorf("invalid regex %q: %v", pattern, err)
+        }
+        m.regexes = append(m.regexes, re)
+    }
+
+    return nil
+}
+
+func legacyMatchers(sig ServiceSignature) []Matcher {
+    var matchers []Matcher
+
+    if sig.BodyMatch != "" {
+        matchers = append(matchers, Matcher{Type: "regex", Regex: []string{sig.BodyMatch}, Name: "Body match"})
+    }
+    if sig.HeaderMatch != "" {
+        matchers = append(matchers, Matcher{Type: "header", Words: []string{sig.HeaderMatch}, Name: "Header match"})
+    }
+    if sig.StatusCode != 0 && sig.BodyMatch == "" {
+        matchers = append(matchers, Matcher{Type: "status", Status: []int{sig.StatusCode}, Name: "Status match"})
+    }
+
+    return matchers
+}
+
+func (sig ServiceSignature) hasMatcherType(matcherType string) bool {
+    for _, m := range sig.compiled {
+        if m.Type == matcherType {
+            return true
+        }
+    }
+    return false
+}
+
+func newHTTPResponse(resp *fasthttp.Response) *httpResponse {
+    r := &httpResponse{
+        status:   resp.StatusCode(),
+        body:     string(resp.Body()),
+        location: string(resp.Header.Peek("Location")),
+    }
+
+    resp.Header.VisitAll(func(key, value []byte) {
+        r.headers = append(r.headers, string(key)+": "+string(value))
+    })
+
+    return r
+}
+
+func evaluateMatchers(sig ServiceSignature, r *httpResponse, baseURL string) (bool, []string) {
+    if len(sig.compiled) == 0 {
+        return false, nil
+    }
+    if sig.exclude != nil && sig.exclude.match(r, baseURL) {
+        return false, nil
+    }
+
+    and := strings.EqualFold(sig.MatchersCondition, "and")
+    var evidence []string
+
+    for _, m := range sig.compiled {
+        matched := m.match(r, baseURL)
+        if m.Negative {
+            matched = !matched
+        }
+
+        if matched {
+            evidence = append(evidence, m.describe())
+            if !and {
+                return true, evidence
+            }
+        } else if and {
+            return false, nil
+        }
+    }
+
+    return and, evidence
+}
+
+func (m Matcher) match(r *httpResponse, baseURL string) bool {
+    switch m.Type {
+    case "status":
+        return m.matchAll(len(m.Status), func(i int) bool { return r.status == m.Status[i] })
+    case "body-length":
+        return m.matchAll(len(m.Size), func(i int) bool { return len(r.body) == m.Size[i] })
+    case "favicon":
+        hash, ok := r.faviconHash(baseURL)
+        if !ok {
+            return false
+        }
+        return m.matchAll(len(m.Hash), func(i int) bool { return hash == m.Hash[i] })
+    case "header":
+        return m.matchText(strings.Join(r.headers, "\n"), r.headers)
+    case "redirect":
+        if r.location == "" {
+            return false
+        }
+        return m.matchText(r.location, nil)
+    default:
+        switch m.Part {
+        case "header":
+            return m.matchText(strings.Join(r.headers, "\n"), nil)
+        case "all":
+            return m.matchText(strings.Join(r.headers, "\n")+"\n\n"+r.body, nil)
+        }
+        return m.matchText(r.body, nil)
+    }
+}
+
+func (m Matcher) matchText(text string, headers []string) bool {
+    words := m.Words
+    if m.Type == "regex" {
+        words = nil
+    }
+
+    var checks []func() bool
+    for _, word := range words {
+        word := word
+        checks = append(checks, func() bool {
+            if m.containsWord(text, word) {
+                return true
+            }
+            for _, header := range headers {
+                if m.containsWord(strings.Replace(header, ": ", ":", 1), word) {
+                    return true
+                }
+            }
+            return false
+        })
+    }
+    for _, re := range m.regexes {
+        re := re
+        checks = append(checks, func() bool { return re.MatchString(text) })
+    }
+
+    return m.matchAll(len(checks), func(i int) bool { return checks[i]() })
+}
+
+func (m Matcher) containsWord(text, word string) bool {
+    if m.CaseInsensitive {
+        return strings.Contains(strings.ToLower(text), strings.ToLower(word))
+    }
+    return strings.Contains(text, word)
+}
+
+func (m Matcher) matchAll(n int, check func(int) bool) bool {
+    and := strings.EqualFold(m.Condition, "and")
+    for i := 0; i < n; i++ {
+        if check(i) {
+            if !and {
+                return true
+            }
+        } else if and {
+            return false
+        }
+    }
+    return and && n > 0
+}
+
+func (m Matcher) describe() string {
+    name := m.Name
+    if name == "" {
+        name = strings.ToUpper(m.Type[:1]) + m.Type[1:] + " match"
+    }
+    if m.Negative {
+        name = "NOT " + name
+    }
+    return name
+}
+
+func (r *httpResponse) snippet(sig ServiceSignature) string {
+    for _, m := range sig.compiled {
+        if m.Negative || (m.Type != "word" && m.Type != "regex") || m.Part == "header" {
+            continue
+        }
+
+        var loc []int
+        for _, word := range m.Words {
+            text, find := r.body, word
+            if m.CaseInsensitive {
+                text, find = strings.ToLower(text), strings.ToLower(word)
+            }
+            if i := strings.Index(text, find); i >= 0 {
+                loc = []int{i, i + len(find)}
+                break
+            }
+        }
+        for _, re := range m.regexes {
+            if loc != nil {
+                break
+            }
+            loc = re.FindStringIndex(r.body)
+        }
+        if loc == nil {
+            continue
+        }
+
+        start, end := loc[0]-snippetContext, loc[1]+snippetContext
+        if start < 0 {
+            start = 0
+        }
+        if end > len(r.body) {
+            end = len(r.body)
+        }
+        return strings.ToValidUTF8(strings.Join(strings.Fields(r.body[start:end]), " "), "")
+    }
+    return ""
+}
+
+func (r *httpResponse) faviconHash(baseURL string) (int32, bool) {
+    if r.favicon != nil {
+        return *r.favicon, true
+    }
+
+    req := fasthttp.AcquireRequest()
+    resp := fasthttp.AcquireResponse()
+    defer fasthttp.ReleaseRequest(req)
+    defer fasthttp.ReleaseResponse(resp)
+
+    req.SetRequestURI(strings.TrimSuffix(baseURL, "/") + "/favicon.ico")
+    req.Header.SetMethod("GET")
+    req.Header.SetUserAgent(config.UserAgent)
+
+    if err := client.DoRedirects(req, resp, maxRedirects); err != nil || resp.StatusCode() != 200 || len(resp.Body()) == 0 {
+        return 0, false
+    }
+
+    hash := faviconHash(resp.Body())
+    r.favicon = &hash
+    return hash, true
+}
+
+func faviconHash(data []byte) int32 {
+    encoded := base64.StdEncoding.EncodeToString(data)
+
+    var b strings.Builder
+    for len(encoded) > 76 {
+        b.WriteString(encoded[:76])
+        b.WriteByte('\n')
+        encoded = encoded[76:]
+    }
+    b.WriteString(encoded)
+    b.WriteByte('\n')
+
+    return int32(murmur3([]byte(b.String()), 0))
+}
+
+func murmur3(data []byte, seed uint32) uint32 {
+    const c1, c2 = 0xcc9e2d51, 0x1b873593
+
+    h := seed
+    n := len(data) / 4
+    for i := 0; i < n; i++ {
+        k := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+        k *= c1
+        k = k<<15 | k>>17
+        k *= c2
+        h ^= k
+        h = h<<13 | h>>19
+        h = h*5 + 0xe6546b64
+    }
+
+    var k uint32
+    tail := data[n*4:]
+    switch len(tail) {
+    case 3:
+        k ^= uint32(tail[2]) << 16
+        fallthrough
+    case 2:
+        k ^= uint32(tail[1]) << 8
+        fallthrough
+    case 1:
+        k ^= uint32(tail[0])
+        k *= c1
+        k = k<<15 | k>>17
+        k *= c2
+        h ^= k
+    }
+
+    h ^= uint32(len(data))
+    h ^= h >> 16
+    h *= 0x85ebca6b
+    h ^= h >> 13
+    h *= 0xc2b2ae35
+    h ^= h >> 16
+    return h
+}
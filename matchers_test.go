@@ -0,0 +1,284 @@
+package main
+
+import (
+    "regexp"
+    "strings"
+    "testing"
+)
+
+func compiledSignature(t *testing.T, sig ServiceSignature) ServiceSignature {
+    t.Helper()
+
+    if err := compileSignature(&sig); err != nil {
+        t.Fatalf("compileSignature(%s): %v", sig.Service, err)
+    }
+    return sig
+}
+
+func favicon(hash int32) *int32 {
+    return &hash
+}
+
+func TestEvaluateMatchers(t *testing.T) {
+    notFound := &httpResponse{status: 404, headers: []string{"Server: nginx", "Content-Type: text/html"}, body: "<h1>No Such Bucket</h1>"}
+    ok := &httpResponse{status: 200, headers: []string{"Server: nginx"}, body: "<h1>Welcome</h1>"}
+
+    tests := []struct {
+        name     string
+        sig      ServiceSignature
+        response *httpResponse
+        want     bool
+    }{
+        {
+            name:     "or matches on any matcher",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "status", Status: []int{500}}, {Type: "word", Words: []string{"No Such Bucket"}}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "or fails when nothing matches",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "status", Status: []int{500}}, {Type: "word", Words: []string{"gone"}}}},
+            response: notFound,
+            want:     false,
+        },
+        {
+            name:     "and needs every matcher",
+            sig:      ServiceSignature{MatchersCondition: "and", Matchers: []Matcher{{Type: "status", Status: []int{404}}, {Type: "word", Words: []string{"No Such Bucket"}}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "and fails on one miss",
+            sig:      ServiceSignature{MatchersCondition: "and", Matchers: []Matcher{{Type: "status", Status: []int{404}}, {Type: "word", Words: []string{"gone"}}}},
+            response: notFound,
+            want:     false,
+        },
+        {
+            name:     "word condition and",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"No Such", "Bucket"}, Condition: "and"}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "word condition and with a missing word",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"No Such", "gone"}, Condition: "and"}}},
+            response: notFound,
+            want:     false,
+        },
+        {
+            name:     "negative matcher inverts",
+            sig:      ServiceSignature{MatchersCondition: "and", Matchers: []Matcher{{Type: "status", Status: []int{404}}, {Type: "word", Words: []string{"Welcome"}, Negative: true}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "negative matcher vetoes",
+            sig:      ServiceSignature{MatchersCondition: "and", Matchers: []Matcher{{Type: "status", Status: []int{200}}, {Type: "word", Words: []string{"Welcome"}, Negative: true}}},
+            response: ok,
+            want:     false,
+        },
+        {
+            name:     "words are case sensitive by default",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"no such bucket"}}}},
+            response: notFound,
+            want:     false,
+        },
+        {
+            name:     "case insensitive words",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"no such bucket"}, CaseInsensitive: true}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "case insensitive regex",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "regex", Regex: []string{"no such \\w+"}, CaseInsensitive: true}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "word on headers part",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"Server: nginx"}, Part: "header"}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "word on body does not see headers",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"Server: nginx"}}}},
+            response: notFound,
+            want:     false,
+        },
+        {
+            name:     "word on all part",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "word", Words: []string{"nginx", "Bucket"}, Condition: "and", Part: "all"}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "header matcher without space after colon",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "header", Words: []string{"Server:nginx"}}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "body length",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "body-length", Size: []int{len(notFound.body)}}}},
+            response: notFound,
+            want:     true,
+        },
+        {
+            name:     "favicon hash matches",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "favicon", Hash: []int32{116323821}}}},
+            response: &httpResponse{status: 200, favicon: favicon(116323821)},
+            want:     true,
+        },
+        {
+            name:     "favicon hash differs",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "favicon", Hash: []int32{116323821}}}},
+            response: &httpResponse{status: 200, favicon: favicon(-1)},
+            want:     false,
+        },
+        {
+            name:     "redirect location",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "redirect", Words: []string{"/login"}}}},
+            response: &httpResponse{status: 302, location: "https://portal.example.net/login"},
+            want:     true,
+        },
+        {
+            name:     "redirect regex",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "redirect", Regex: []string{`^https://[^/]+\.example\.net/`}}}},
+            response: &httpResponse{status: 301, location: "https://portal.example.net/"},
+            want:     true,
+        },
+        {
+            name:     "redirect needs a location",
+            sig:      ServiceSignature{Matchers: []Matcher{{Type: "redirect", Words: []string{"/login"}}}},
+            response: ok,
+            want:     false,
+        },
+        {
+            name:     "body_exclude vetoes legacy match",
+            sig:      ServiceSignature{StatusCode: 404, BodyExclude: "Bucket"},
+            response: notFound,
+            want:     false,
+        },
+        {
+            name:     "body_exclude vetoes matcher signatures",
+            sig:      ServiceSignature{BodyExclude: "Bucket", Matchers: []Matcher{{Type: "status", Status: []int{404}}}},
+            response: notFound,
+            want:     false,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tt.sig.Service = tt.name
+            sig := compiledSignature(t, tt.sig)
+            if got, evidence := evaluateMatchers(sig, tt.response, "https://example.com"); got != tt.want {
+                t.Errorf("evaluateMatchers = %v (evidence %v), want %v", got, evidence, tt.want)
+            }
+        })
+    }
+}
+
+func TestCompileMatcherRejectsInvalid(t *testing.T) {
+    tests := []struct {
+        name    string
+        matcher Matcher
+    }{
+        {"unknown type", Matcher{Type: "dsl"}},
+        {"unknown part", Matcher{Type: "word", Words: []string{"x"}, Part: "cookie"}},
+        {"bad condition", Matcher{Type: "word", Words: []string{"x"}, Condition: "xor"}},
+        {"status out of range", Matcher{Type: "status", Status: []int{99}}},
+        {"empty status", Matcher{Type: "status"}},
+        {"empty words", Matcher{Type: "word"}},
+        {"bad regex", Matcher{Type: "regex", Regex: []string{"("}}},
+        {"empty favicon", Matcher{Type: "favicon"}},
+        {"empty redirect", Matcher{Type: "redirect"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m := tt.matcher
+            if err := compileMatcher(&m); err == nil {
+                t.Errorf("compileMatcher(%+v) succeeded, want an error", tt.matcher)
+            }
+        })
+    }
+
+    sig := ServiceSignature{Service: "x", MatchersCondition: "xor", StatusCode: 404}
+    if err := compileSignature(&sig); err == nil {
+        t.Error("compileSignature accepted matchers_condition xor")
+    }
+}
+
+func baselineVerdict(sig ServiceSignature, r *httpResponse) bool {
+    if sig.BodyMatch != "" {
+        if matched, _ := regexp.MatchString(sig.BodyMatch, r.body); matched {
+            return true
+        }
+    }
+    if sig.HeaderMatch != "" {
+        for _, header := range r.headers {
+            if strings.Contains(strings.Replace(header, ": ", ":", 1), sig.HeaderMatch) {
+                return true
+            }
+        }
+    }
+    return sig.StatusCode != 0 && r.status == sig.StatusCode && sig.BodyMatch == ""
+}
+
+func builtinSignature(t *testing.T, service string) ServiceSignature {
+    t.Helper()
+
+    for _, sig := range signatures {
+        if sig.Service == service {
+            return compiledSignature(t, sig)
+        }
+    }
+    t.Fatalf("no built-in signature %q", service)
+    return ServiceSignature{}
+}
+
+func TestLegacyMatchersKeepBaselineVerdicts(t *testing.T) {
+    responses := map[string]*httpResponse{
+        "heroku no such app":    {status: 404, body: "<iframe src=\"//www.herokucdn.com/error-pages/no-such-app.html\"></iframe> No such app"},
+        "heroku 200 mentioning": {status: 200, body: "Deployed on heroku"},
+        "cloudfront error page": {status: 403, body: "<h1>ERROR: The request could not be satisfied</h1>"},
+        "cloudfront 404 only":   {status: 404, body: "Not Found"},
+        "cloudfront header":     {status: 200, headers: []string{"X-Cache:Error from cloudfront"}, body: "ok"},
+        "plain 404":             {status: 404, body: "nothing here"},
+        "plain 200":             {status: 200, headers: []string{"Server: nginx"}, body: "hello"},
+    }
+
+    for _, service := range []string{"Heroku", "CloudFront", "GitHub Pages", "AWS S3"} {
+        sig := builtinSignature(t, service)
+        for name, response := range responses {
+            got, _ := evaluateMatchers(sig, response, "https://example.com")
+            if want := baselineVerdict(sig, response); got != want {
+                t.Errorf("%s on %q: got %v, baseline gave %v", service, name, got, want)
+            }
+        }
+    }
+}
+
+func TestLegacyHeaderMatchAcceptsSpacedHeaders(t *testing.T) {
+    sig := builtinSignature(t, "CloudFront")
+    response := &httpResponse{status: 200, headers: []string{"X-Cache: Error from cloudfront"}, body: "ok"}
+
+    if matched, _ := evaluateMatchers(sig, response, "https://example.com"); !matched {
+        t.Error("CloudFront header_match did not match the X-Cache header as sent on the wire")
+    }
+}
+
+func TestResponseSnippet(t *testing.T) {
+    body := strings.Repeat("a ", 100) + "NoSuchBucket" + strings.Repeat(" b", 100)
+    sig := compiledSignature(t, ServiceSignature{Service: "s", Matchers: []Matcher{{Type: "word", Words: []string{"NoSuchBucket"}}}})
+
+    snippet := (&httpResponse{body: body}).snippet(sig)
+    if !strings.Contains(snippet, "NoSuchBucket") {
+        t.Fatalf("snippet %q does not contain the match", snippet)
+    }
+    if len(snippet) > len("NoSuchBucket")+2*snippetContext {
+        t.Errorf("snippet is %d bytes, want at most %d", len(snippet), len("NoSuchBucket")+2*snippetContext)
+    }
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+    "fmt"
+    "net"
+    "strings"
+
+    "github.com/miekg/dns"
+)
+
+type nameServerStatus struct {
+    host   string
+    lame   bool
+    reason string
+}
+
+func checkNSDelegation(target scanTarget, result *Result) bool {
+    subdomain := target.name
+    nameServers := target.answer.nameServersOrNil()
+    if len(nameServers) == 0 {
+        var err error
+        nameServers, err = delegatedNameServers(subdomain)
+        if err != nil || len(nameServers) == 0 {
+            return false
+        }
+    }
+
+    var statuses []nameServerStatus
+    lame := 0
+    for _, ns := range nameServers {
+        status := probeNameServer(ns, subdomain)
+        if status.lame {
+            lame++
+        }
+        statuses = append(statuses, status)
+    }
+
+    if lame == 0 {
+        return false
+    }
+
+    var evidence []string
+    for _, status := range statuses {
+        if status.lame {
+            evidence = append(evidence, fmt.Sprintf("%s (%s)", status.host, status.reason))
+        }
+    }
+
+    result.NameServers = nameServers
+    result.CNAME = ""
+    result.Evidence = fmt.Sprintf("Lame delegation %d/%d: %s", lame, len(nameServers), strings.Join(evidence, ", "))
+    result.Status = "potentially_vulnerable"
+
+    signature, ok := matchNSSignature(nameServers)
+    if !ok {
+        result.Service = "Unknown DNS provider"
+        result.Confidence = "low"
+        return true
+    }
+
+    result.Service = signature.Service
+    result.Confidence = signature.Confidence
+    if lame == len(nameServers) {
+        result.Status = "vulnerable"
+    }
+
+    return true
+}
+
+func delegatedNameServers(name string) ([]string, error) {
+    resp, err := queryDNS(name, dns.TypeNS)
+    if err == nil {
+        return nameServersFor(resp, name), nil
+    }
+
+    return referralNameServers(name)
+}
+
+func referralNameServers(name string) ([]string, error) {
+    labels := dns.SplitDomainName(name)
+
+    for i := 1; i < len(labels); i++ {
+        parent := strings.Join(labels[i:], ".")
+        resp, err := queryDNS(parent, dns.TypeNS)
+        if err != nil {
+            continue
+        }
+
+        parentServers := nameServersFor(resp, parent)
+        if len(parentServers) == 0 {
+            continue
+        }
+
+        for _, ns := range parentServers {
+            for _, ip := range lookupIPs(ns) {
+                referral, err := resolver.Direct(net.JoinHostPort(ip.String(), "53"), name, dns.TypeNS)
+                if err != nil {
+                    continue
+                }
+                return nameServersFor(referral, name), nil
+            }
+        }
+
+        return nil, fmt.Errorf("no parent name server for %s answered", parent)
+    }
+
+    return nil, nil
+}
+
+func nameServersFor(resp *dns.Msg, name string) []string {
+    var servers []string
+    seen := make(map[string]bool)
+
+    for _, section := range [][]dns.RR{resp.Answer, resp.Ns} {
+        for _, rr := range section {
+            ns, ok := rr.(*dns.NS)
+            if !ok || !strings.EqualFold(ns.Hdr.Name, dns.Fqdn(name)) {
+                continue
+            }
+            host := strings.ToLower(ns.Ns)
+            if !seen[host] {
+                seen[host] = true
+                servers = append(servers, host)
+            }
+        }
+    }
+
+    return servers
+}
+
+func probeNameServer(host, zone string) nameServerStatus {
+    status := nameServerStatus{host: host}
+
+    ips := lookupIPs(host)
+    if len(ips) == 0 {
+        status.lame = true
+        status.reason = "name server does not resolve"
+        return status
+    }
+
+    resp, err := resolver.Direct(net.JoinHostPort(ips[0].String(), "53"), zone, dns.TypeSOA)
+    switch {
+    case err != nil:
+        status.lame = true
+        status.reason = "no response"
+    case resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused:
+        status.lame = true
+        status.reason = dns.RcodeToString[resp.Rcode]
+    case !resp.Authoritative:
+        status.lame = true
+        status.reason = "not authoritative"
+    }
+
+    return status
+}
+
+func matchNSSignature(nameServers []string) (ServiceSignature, bool) {
+    for _, signature := range signatures {
+        for _, ns := range nameServers {
+            if matchesCNAME(ns, signature.NS) {
+                return signature, true
+            }
+        }
+    }
+    return ServiceSignature{}, false
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+
+    "github.com/fatih/color"
+    "gopkg.in/yaml.v3"
+)
+
+type nucleiTemplate struct {
+    ID   string `yaml:"id"`
+    Info struct {
+        Name      string      `yaml:"name"`
+        Severity  string      `yaml:"severity"`
+        Tags      string      `yaml:"tags"`
+        Reference interface{} `yaml:"reference"`
+    } `yaml:"info"`
+    HTTP     []nucleiRequest `yaml:"http"`
+    Requests []nucleiRequest `yaml:"requests"`
+    DNS      []interface{}   `yaml:"dns"`
+}
+
+type nucleiRequest struct {
+    Method            string          `yaml:"method"`
+    Path              []string        `yaml:"path"`
+    Raw               []string        `yaml:"raw"`
+    MatchersCondition string          `yaml:"matchers-condition"`
+    Matchers          []nucleiMatcher `yaml:"matchers"`
+}
+
+type nucleiMatcher struct {
+    Type            string   `yaml:"type"`
+    Part            string   `yaml:"part"`
+    Words           []string `yaml:"words"`
+    Regex           []string `yaml:"regex"`
+    Status          []int    `yaml:"status"`
+    Size            []int    `yaml:"size"`
+    DSL             []string `yaml:"dsl"`
+    Condition       string   `yaml:"condition"`
+    Negative        bool     `yaml:"negative"`
+    CaseInsensitive bool     `yaml:"case-insensitive"`
+}
+
+var hostNotIPRegex = regexp.MustCompile(`^\s*Host\s*!=\s*ip\s*$`)
+
+func importNucleiTemplates(path string, cnameMap map[string][]string) ([]ServiceSignature, []string, error) {
+    files, err := collectFiles(path, ".yaml", ".yml")
+    if err != nil {
+        return nil, nil, fmt.Errorf("nuclei templates %s: %v", path, err)
+    }
+
+    var imported []ServiceSignature
+    var skipped []string
+    seen := make(map[string]bool)
+
+    for _, file := range files {
+        sig, problems, err := convertNucleiTemplate(file, cnameMap)
+        if err != nil {
+            skipped = append(skipped, fmt.Sprintf("%s: %v", file, err))
+            continue
+        }
+        if len(problems) == 0 && seen[strings.ToLower(sig.Service)] {
+            problems = append(problems, fmt.Sprintf("duplicate service %q", sig.Service))
+        }
+        if len(problems) == 0 {
+            if err := validateSignature(sig); err != nil {
+                problems = append(problems, err.Error())
+            }
+        }
+        if len(problems) > 0 {
+            skipped = append(skipped, fmt.Sprintf("%s: %s", file, strings.Join(problems, "; ")))
+            continue
+        }
+
+        seen[strings.ToLower(sig.Service)] = true
+        sig.Source = file
+        imported = append(imported, sig)
+    }
+
+    return imported, skipped, nil
+}
+
+func convertNucleiTemplate(file string, cnameMap map[string][]string) (ServiceSignature, []string, error) {
+    data, err := os.ReadFile(file)
+    if err != nil {
+        return ServiceSignature{}, nil, err
+    }
+
+    var tmpl nucleiTemplate
+    if err := yaml.Unmarshal(data, &tmpl); err != nil {
+        return ServiceSignature{}, nil, fmt.Errorf("parsing template: %v", err)
+    }
+
+    sig := ServiceSignature{
+        Service:    nucleiServiceName(tmpl),
+        Confidence: nucleiConfidence(tmpl.Info.Severity),
+        References: nucleiReferences(tmpl.Info.Reference),
+    }
+
+    var problems []string
+
+    requests := append(tmpl.HTTP, tmpl.Requests...)
+    if len(tmpl.DNS) > 0 {
+        problems = append(problems, "dns requests are not supported")
+    }
+    if len(requests) != 1 {
+        problems = append(problems, fmt.Sprintf("expected exactly one http request, found %d", len(requests)))
+        return sig, problems, nil
+    }
+
+    req := requests[0]
+    if len(req.Raw) > 0 {
+        problems = append(problems, "raw requests are not supported")
+    }
+    if req.Method != "" && !strings.EqualFold(req.Method, "GET") {
+        problems = append(problems, fmt.Sprintf("method %s is not supported", req.Method))
+    }
+    for _, p := range req.Path {
+        if p != "{{BaseURL}}" && p != "{{BaseURL}}/" {
+            problems = append(problems, fmt.Sprintf("path %q is not supported", p))
+        }
+    }
+
+    condition := strings.ToLower(req.MatchersCondition)
+    if condition == "" {
+        condition = "or"
+    }
+
+    problems = append(problems, applyNucleiMatchers(&sig, req.Matchers, condition)...)
+
+    sig.CNAMES = nucleiCNAMEs(tmpl, sig.Service, cnameMap)
+    if len(sig.CNAMES) == 0 {
+        problems = append(problems, "no CNAME patterns known for this template (add its id to -nuclei-cnames)")
+    }
+
+    return sig, problems, nil
+}
+
+func applyNucleiMatchers(sig *ServiceSignature, matchers []nucleiMatcher, condition string) []string {
+    var problems []string
+    var fingerprints []string
+
+    for _, m := range matchers {
+        matcher := Matcher{
+            Condition:       strings.ToLower(m.Condition),
+            Negative:        m.Negative,
+            CaseInsensitive: m.CaseInsensitive,
+        }
+
+        switch m.Type {
+        case "dsl":
+            for _, expr := range m.DSL {
+                if !hostNotIPRegex.MatchString(expr) {
+                    problems = append(problems, fmt.Sprintf("dsl expression %q is not supported", expr))
+                }
+            }
+            continue
+        case "status":
+            matcher.Type = "status"
+            matcher.Status = m.Status
+        case "size":
+            matcher.Type = "body-length"
+            matcher.Size = m.Size
+        case "word", "regex":
+            matcher.Type = m.Type
+            matcher.Words = m.Words
+            matcher.Regex = m.Regex
+
+            switch strings.ToLower(m.Part) {
+            case "", "body":
+            case "header":
+                matcher.Part = "header"
+            case "all", "response":
+                matcher.Part = "all"
+            default:
+                problems = append(problems, fmt.Sprintf("matcher part %q is not supported", m.Part))
+                continue
+            }
+
+            if !m.Negative && matcher.Part != "header" {
+                fingerprints = append(fingerprints, m.Words...)
+                fingerprints = append(fingerprints, m.Regex...)
+            }
+        default:
+            problems = append(problems, fmt.Sprintf("matcher type %q is not supported", m.Type))
+            continue
+        }
+
+        sig.Matchers = append(sig.Matchers, matcher)
+    }
+
+    if len(sig.Matchers) == 0 {
+        problems = append(problems, "no supported matchers")
+    }
+
+    sig.MatchersCondition = condition
+    sig.Fingerprint = strings.Join(fingerprints, " | ")
+
+    return problems
+}
+
+func nucleiServiceName(tmpl nucleiTemplate) string {
+    name := strings.TrimSpace(tmpl.Info.Name)
+    for _, suffix := range []string{"takeover detection", "subdomain takeover", "takeover"} {
+        if strings.HasSuffix(strings.ToLower(name), suffix) {
+            name = strings.TrimSpace(name[:len(name)-len(suffix)])
+        }
+    }
+    name = strings.TrimSpace(strings.TrimSuffix(name, "-"))
+    if name == "" {
+        name = strings.TrimSuffix(tmpl.ID, "-takeover")
+    }
+    return name
+}
+
+func nucleiConfidence(severity string) string {
+    switch strings.ToLower(severity) {
+    case "critical", "high":
+        return "high"
+    case "medium":
+        return "medium"
+    default:
+        return "low"
+    }
+}
+
+func nucleiReferences(ref interface{}) []string {
+    switch v := ref.(type) {
+    case string:
+        return []string{v}
+    case []interface{}:
+        var refs []string
+        for _, item := range v {
+            if s, ok := item.(string); ok {
+                refs = append(refs, s)
+            }
+        }
+        return refs
+    }
+    return nil
+}
+
+func nucleiCNAMEs(tmpl nucleiTemplate, service string, cnameMap map[string][]string) []string {
+    keys := []string{normalizeServiceName(tmpl.ID), normalizeServiceName(service)}
+
+    for _, key := range keys {
+        if cnames, ok := cnameMap[key]; ok && key != "" {
+            return append([]string(nil), cnames...)
+        }
+    }
+
+    for _, key := range keys {
+        if key == "" {
+            continue
+        }
+        for _, sig := range signatures {
+            if normalizeServiceName(sig.Service) == key {
+                return append([]string(nil), sig.CNAMES...)
+            }
+        }
+    }
+
+    return nil
+}
+
+func loadNucleiCNAMEMap(path string) (map[string][]string, error) {
+    cnameMap := make(map[string][]string)
+    if path == "" {
+        return cnameMap, nil
+    }
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading nuclei cname map: %v", err)
+    }
+
+    var raw map[string][]string
+    if err := yaml.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("parsing %s: %v", path, err)
+    }
+
+    for key, cnames := range raw {
+        if len(cnames) == 0 {
+            return nil, fmt.Errorf("%s: %q has no cname patterns", path, key)
+        }
+        cnameMap[normalizeServiceName(key)] = cnames
+    }
+
+    return cnameMap, nil
+}
+
+func runNucleiImport(path, cnameMapPath string) {
+    cnameMap, err := loadNucleiCNAMEMap(cnameMapPath)
+    if err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+
+    imported, skipped, err := importNucleiTemplates(path, cnameMap)
+    if err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+
+    for _, line := range skipped {
+        fmt.Fprintln(os.Stderr, yellow("[~] Unsupported "+line))
+    }
+
+    if err := writeImportedSignatures(imported, config.OutputFile); err != nil {
+        color.Red("[-] Error writing imported signatures: %v", err)
+        os.Exit(1)
+    }
+
+    fmt.Fprintln(os.Stderr, cyan(fmt.Sprintf("[+] Imported %d templates, %d unsupported", len(imported), len(skipped))))
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/fatih/color"
+)
+
+const outputFlushInterval = 2 * time.Second
+
+type resultWriter interface {
+    Write(result Result) error
+    Flush() error
+    Close() error
+}
+
+type outputSpec struct {
+    filename string
+    format   string
+}
+
+type scanStats struct {
+    total      int
+    vulnerable int
+    potential  int
+    wildcard   int
+}
+
+type resultSinks struct {
+    mu      sync.Mutex
+    writers []resultWriter
+    names   []string
+    done    chan struct{}
+}
+
+var (
+    sinks             *resultSinks
+    stats             scanStats
+    keepResults       bool
+    reportTemplateDir string
+    scanFinished      bool
+)
+
+var slugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+var csvHeader = []string{
+    "subdomain", "cname", "service", "status", "confidence", "evidence", "ip", "response_time",
+    "record_type", "cloud_provider", "cloud_region", "zone_id", "resource", "http_status", "snippet",
+}
+
+func outputFormat(filename string, jsonOutput bool) string {
+    switch strings.ToLower(filepath.Ext(filename)) {
+    case ".jsonl", ".ndjson":
+        return "jsonl"
+    case ".csv":
+        return "csv"
+    case ".json":
+        return "json"
+    case ".txt", ".log":
+        return "text"
+    case ".sarif":
+        return "sarif"
+    case ".html", ".htm":
+        return "html"
+    }
+    if jsonOutput {
+        return "json"
+    }
+    return "csv"
+}
+
+func openSinks(specs []outputSpec) (*resultSinks, error) {
+    s := &resultSinks{done: make(chan struct{})}
+
+    for _, spec := range specs {
+        writer, err := newResultWriter(spec)
+        if err != nil {
+            s.Close()
+            return nil, err
+        }
+        s.writers = append(s.writers, writer)
+        s.names = append(s.names, spec.filename)
+    }
+
+    go s.flushLoop()
+    return s, nil
+}
+
+func newResultWriter(spec outputSpec) (resultWriter, error) {
+    switch spec.format {
+    case "markdown":
+        return newReportWriter(spec.filename, reportTemplateDir)
+    case "sqlite":
+        return newSqliteWriter(spec.filename)
+    }
+
+    file, err := os.Create(spec.filename)
+    if err != nil {
+        return nil, fmt.Errorf("creating output file: %v", err)
+    }
+
+    buffered := bufio.NewWriter(file)
+    switch spec.format {
+    case "jsonl":
+        return &jsonlWriter{file: file, w: buffered}, nil
+    case "json":
+        return &jsonArrayWriter{file: file, w: buffered}, nil
+    case "text":
+        return &textWriter{file: file, w: buffered}, nil
+    case "sarif":
+        return newSarifWriter(file), nil
+    case "html":
+        return &htmlWriter{file: file}, nil
+    }
+
+    w := &csvWriter{file: file, buffered: buffered, w: csv.NewWriter(buffered)}
+    if err := w.w.Write(csvHeader); err != nil {
+        file.Close()
+        return nil, err
+    }
+    return w, nil
+}
+
+func (s *resultSinks) Write(result Result) {
+    if s == nil {
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, writer := range s.writers {
+        if err := writer.Write(result); err != nil {
+            color.Red("[-] Error writing %s: %v", s.names[i], err)
+        }
+    }
+}
+
+func (s *resultSinks) flushLoop() {
+    ticker := time.NewTicker(outputFlushInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+            s.mu.Lock()
+            for _, writer := range s.writers {
+                writer.Flush()
+            }
+            s.mu.Unlock()
+        case <-s.done:
+            return
+        }
+    }
+}
+
+func (s *resultSinks) Close() {
+    if s == nil {
+        return
+    }
+
+    close(s.done)
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, writer := range s.writers {
+        if err := writer.Close(); err != nil {
+            color.Red("[-] Error writing %s: %v", s.names[i], err)
+            continue
+        }
+        color.Green("[+] Results saved to: %s", s.names[i])
+    }
+}
+
+func recordResult(result Result) {
+    resultsLock.Lock()
+    stats.total++
+    switch result.Status {
+    case "vulnerable":
+        stats.vulnerable++
+    case "potentially_vulnerable":
+        stats.potential++
+    case "wildcard":
+        stats.wildcard++
+    }
+    if keepResults {
+        results = append(results, result)
+    }
+    resultsLock.Unlock()
+
+    sinks.Write(result)
+}
+
+type jsonlWriter struct {
+    file *os.File
+    w    *bufio.Writer
+}
+
+func (j *jsonlWriter) Write(result Result) error {
+    line, err := json.Marshal(result)
+    if err != nil {
+        return err
+    }
+    _, err = j.w.Write(append(line, '\n'))
+    return err
+}
+
+func (j *jsonlWriter) Flush() error {
+    return j.w.Flush()
+}
+
+func (j *jsonlWriter) Close() error {
+    return closeBuffered(j.w, j.file)
+}
+
+type jsonArrayWriter struct {
+    file  *os.File
+    w     *bufio.Writer
+    count int
+}
+
+func (j *jsonArrayWriter) Write(result Result) error {
+    data, err := json.MarshalIndent(result, "  ", "  ")
+    if err != nil {
+        return err
+    }
+
+    separator := ",\n  "
+    if j.count == 0 {
+        separator = "[\n  "
+    }
+    j.count++
+
+    j.w.WriteString(separator)
+    _, err = j.w.Write(data)
+    return err
+}
+
+func (j *jsonArrayWriter) Flush() error {
+    return j.w.Flush()
+}
+
+func (j *jsonArrayWriter) Close() error {
+    if j.count == 0 {
+        j.w.WriteString("[]\n")
+    } else {
+        j.w.WriteString("\n]\n")
+    }
+    return closeBuffered(j.w, j.file)
+}
+
+type csvWriter struct {
+    file     *os.File
+    buffered *bufio.Writer
+    w        *csv.Writer
+}
+
+func (c *csvWriter) Write(result Result) error {
+    return c.w.Write([]string{
+        result.Subdomain, result.CNAME, result.Service, result.Status, result.Confidence, result.Evidence,
+        result.IP, strconv.FormatInt(result.ResponseTime, 10), result.RecordType,
+        result.CloudProvider, result.CloudRegion, result.ZoneID, result.Resource,
+        httpStatus(result), result.Snippet,
+    })
+}
+
+func (c *csvWriter) Flush() error {
+    c.w.Flush()
+    if err := c.w.Error(); err != nil {
+        return err
+    }
+    return c.buffered.Flush()
+}
+
+func (c *csvWriter) Close() error {
+    c.w.Flush()
+    if err := c.w.Error(); err != nil {
+        c.file.Close()
+        return err
+    }
+    return closeBuffered(c.buffered, c.file)
+}
+
+type textWriter struct {
+    file *os.File
+    w    *bufio.Writer
+}
+
+func (t *textWriter) Write(result Result) error {
+    _, err := fmt.Fprintln(t.w, formatResult(result))
+    return err
+}
+
+func (t *textWriter) Flush() error {
+    return t.w.Flush()
+}
+
+func (t *textWriter) Close() error {
+    return closeBuffered(t.w, t.file)
+}
+
+func formatResult(result Result) string {
+    switch result.Status {
+    case "vulnerable":
+        return fmt.Sprintf("[VULNERABLE] %s -> %s (%s) [%s] %s",
+            result.Subdomain, resultTarget(result), result.Service, result.Confidence, result.Evidence)
+    case "potentially_vulnerable":
+        return fmt.Sprintf("[POTENTIAL] %s -> %s (%s) [%s] %s",
+            result.Subdomain, resultTarget(result), result.Service, result.Confidence, result.Evidence)
+    case "wildcard":
+        return fmt.Sprintf("[WILDCARD] %s -> %s (%s) %s", result.Subdomain, resultTarget(result), result.Service, result.Evidence)
+    }
+    return fmt.Sprintf("[SAFE] %s", result.Subdomain)
+}
+
+func serviceSlug(service string) string {
+    return strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(service), "-"), "-")
+}
+
+func httpStatus(result Result) string {
+    if result.HTTPStatus == 0 {
+        return ""
+    }
+    return strconv.Itoa(result.HTTPStatus)
+}
+
+func closeBuffered(w *bufio.Writer, file *os.File) error {
+    if err := w.Flush(); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
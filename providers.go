@@ -0,0 +1,228 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+
+    "github.com/miekg/dns"
+)
+
+var providerFormats = map[string]bool{
+    "route53":    true,
+    "cloudflare": true,
+    "azure":      true,
+    "gcloud":     true,
+}
+
+type route53Export struct {
+    ResourceRecordSets []struct {
+        Name            string `json:"Name"`
+        Type            string `json:"Type"`
+        TTL             uint32 `json:"TTL"`
+        ResourceRecords []struct {
+            Value string `json:"Value"`
+        } `json:"ResourceRecords"`
+        AliasTarget *struct {
+            HostedZoneId string `json:"HostedZoneId"`
+            DNSName      string `json:"DNSName"`
+        } `json:"AliasTarget"`
+    } `json:"ResourceRecordSets"`
+}
+
+type cloudflareExport struct {
+    Result []struct {
+        ZoneID   string `json:"zone_id"`
+        ZoneName string `json:"zone_name"`
+        Name     string `json:"name"`
+        Type     string `json:"type"`
+        Content  string `json:"content"`
+        Priority int    `json:"priority"`
+        TTL      uint32 `json:"ttl"`
+    } `json:"result"`
+}
+
+type azureRecordSet struct {
+    ID          string `json:"id"`
+    Name        string `json:"name"`
+    FQDN        string `json:"fqdn"`
+    Type        string `json:"type"`
+    TTL         uint32 `json:"TTL"`
+    CNAMERecord *struct {
+        CNAME string `json:"cname"`
+    } `json:"cnameRecord"`
+    ARecords []struct {
+        IPv4Address string `json:"ipv4Address"`
+    } `json:"aRecords"`
+    AAAARecords []struct {
+        IPv6Address string `json:"ipv6Address"`
+    } `json:"aaaaRecords"`
+    NSRecords []struct {
+        NSDName string `json:"nsdname"`
+    } `json:"nsRecords"`
+    MXRecords []struct {
+        Exchange   string `json:"exchange"`
+        Preference int    `json:"preference"`
+    } `json:"mxRecords"`
+}
+
+type gcloudRecordSet struct {
+    Name    string   `json:"name"`
+    Type    string   `json:"type"`
+    TTL     uint32   `json:"ttl"`
+    RRDatas []string `json:"rrdatas"`
+}
+
+func readProviderExport(filename, format string) (*zoneData, error) {
+    input, err := openTargets(filename)
+    if err != nil {
+        return nil, fmt.Errorf("opening %s export: %v", format, err)
+    }
+    defer input.Close()
+
+    data, err := io.ReadAll(input)
+    if err != nil {
+        return nil, fmt.Errorf("reading %s export: %v", format, err)
+    }
+
+    var zone *zoneData
+    switch format {
+    case "route53":
+        zone, err = parseRoute53Export(data)
+    case "cloudflare":
+        zone, err = parseCloudflareExport(data, filename)
+    case "azure":
+        zone, err = parseAzureExport(data)
+    case "gcloud":
+        zone, err = parseGcloudExport(data)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("parsing %s export: %v", format, err)
+    }
+
+    if zone.apex == "" {
+        for owner := range zone.ns {
+            if zone.apex == "" || dns.CountLabel(owner) < dns.CountLabel(zone.apex) {
+                zone.apex = owner
+            }
+        }
+    }
+    return zone, nil
+}
+
+func parseRoute53Export(data []byte) (*zoneData, error) {
+    var export route53Export
+    if err := json.Unmarshal(data, &export); err != nil {
+        return nil, err
+    }
+
+    zone := newZoneData("")
+    for _, set := range export.ResourceRecordSets {
+        name := strings.ReplaceAll(set.Name, `\052`, "*")
+
+        if set.AliasTarget != nil {
+            addRecord(zone, name, set.TTL, "CNAME", set.AliasTarget.DNSName)
+            continue
+        }
+        for _, record := range set.ResourceRecords {
+            addRecord(zone, name, set.TTL, set.Type, record.Value)
+        }
+    }
+    return zone, nil
+}
+
+func parseCloudflareExport(data []byte, filename string) (*zoneData, error) {
+    if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+        return parseZoneData(bytes.NewReader(data), "", filename)
+    }
+
+    var export cloudflareExport
+    if err := json.Unmarshal(data, &export); err != nil {
+        return nil, err
+    }
+
+    zone := newZoneData("")
+    for _, record := range export.Result {
+        if zone.apex == "" && record.ZoneName != "" {
+            zone.apex = strings.ToLower(dns.Fqdn(record.ZoneName))
+            zone.zoneID = record.ZoneID
+        }
+
+        value := record.Content
+        if strings.EqualFold(record.Type, "MX") {
+            value = fmt.Sprintf("%d %s", record.Priority, record.Content)
+        }
+        addRecord(zone, record.Name, record.TTL, record.Type, value)
+    }
+    return zone, nil
+}
+
+func parseAzureExport(data []byte) (*zoneData, error) {
+    var sets []azureRecordSet
+    if err := json.Unmarshal(data, &sets); err != nil {
+        return nil, err
+    }
+
+    zone := newZoneData("")
+    for _, set := range sets {
+        if set.Name == "@" {
+            zone.apex = strings.ToLower(dns.Fqdn(set.FQDN))
+        }
+        if zone.zoneID == "" {
+            if i := strings.Index(strings.ToLower(set.ID), "/dnszones/"); i >= 0 {
+                end := strings.Index(set.ID[i+len("/dnszones/"):], "/")
+                if end >= 0 {
+                    zone.zoneID = set.ID[:i+len("/dnszones/")+end]
+                }
+            }
+        }
+
+        if set.CNAMERecord != nil {
+            addRecord(zone, set.FQDN, set.TTL, "CNAME", set.CNAMERecord.CNAME)
+        }
+        for _, record := range set.ARecords {
+            addRecord(zone, set.FQDN, set.TTL, "A", record.IPv4Address)
+        }
+        for _, record := range set.AAAARecords {
+            addRecord(zone, set.FQDN, set.TTL, "AAAA", record.IPv6Address)
+        }
+        for _, record := range set.NSRecords {
+            addRecord(zone, set.FQDN, set.TTL, "NS", record.NSDName)
+        }
+        for _, record := range set.MXRecords {
+            addRecord(zone, set.FQDN, set.TTL, "MX", fmt.Sprintf("%d %s", record.Preference, record.Exchange))
+        }
+    }
+    return zone, nil
+}
+
+func parseGcloudExport(data []byte) (*zoneData, error) {
+    var sets []gcloudRecordSet
+    if err := json.Unmarshal(data, &sets); err != nil {
+        return nil, err
+    }
+
+    zone := newZoneData("")
+    for _, set := range sets {
+        for _, value := range set.RRDatas {
+            addRecord(zone, set.Name, set.TTL, set.Type, value)
+        }
+    }
+    return zone, nil
+}
+
+func addRecord(zone *zoneData, name string, ttl uint32, rtype, value string) {
+    switch strings.ToUpper(rtype) {
+    case "SOA", "CNAME", "A", "AAAA", "NS", "MX":
+    default:
+        return
+    }
+
+    rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(name), ttl, strings.ToUpper(rtype), value))
+    if err != nil || rr == nil {
+        return
+    }
+    zone.add(rr)
+}
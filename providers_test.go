@@ -0,0 +1,182 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+const route53Fixture = `{
+    "ResourceRecordSets": [
+        {"Name": "example.com.", "Type": "SOA", "TTL": 900, "ResourceRecords": [{"Value": "ns-1.awsdns-00.com. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"}]},
+        {"Name": "example.com.", "Type": "NS", "TTL": 172800, "ResourceRecords": [{"Value": "ns-1.awsdns-00.com."}]},
+        {"Name": "www.example.com.", "Type": "CNAME", "TTL": 300, "ResourceRecords": [{"Value": "app.herokuapp.com"}]},
+        {"Name": "assets.example.com.", "Type": "A", "AliasTarget": {"HostedZoneId": "Z3AQBSTGFYJSTF", "DNSName": "assets.example.com.s3-website-us-east-1.amazonaws.com.", "EvaluateTargetHealth": false}},
+        {"Name": "\\052.dev.example.com.", "Type": "CNAME", "TTL": 60, "ResourceRecords": [{"Value": "dev.azurewebsites.net"}]},
+        {"Name": "txt.example.com.", "Type": "TXT", "TTL": 60, "ResourceRecords": [{"Value": "\"v=spf1 -all\""}]}
+    ]
+}`
+
+const cloudflareAPIFixture = `{
+    "result": [
+        {"id": "1", "zone_id": "023e105f4ecef8ad9ca31a8372d0c353", "zone_name": "example.com", "name": "www.example.com", "type": "CNAME", "content": "app.herokuapp.com", "ttl": 1},
+        {"id": "2", "zone_id": "023e105f4ecef8ad9ca31a8372d0c353", "zone_name": "example.com", "name": "example.com", "type": "MX", "content": "mx.example.net", "priority": 10, "ttl": 1},
+        {"id": "3", "zone_id": "023e105f4ecef8ad9ca31a8372d0c353", "zone_name": "example.com", "name": "api.example.com", "type": "A", "content": "192.0.2.1", "ttl": 1}
+    ],
+    "success": true
+}`
+
+const cloudflareBINDFixture = `;; Exported from Cloudflare
+example.com.	3600	IN	SOA	ns.cloudflare.com. dns.cloudflare.com. 1 10000 2400 604800 3600
+www.example.com.	1	IN	CNAME	app.herokuapp.com.
+api.example.com.	1	IN	A	192.0.2.1
+`
+
+const azureFixture = `[
+    {"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.com/NS/@", "name": "@", "fqdn": "example.com.", "type": "Microsoft.Network/dnszones/NS", "TTL": 172800, "nsRecords": [{"nsdname": "ns1-01.azure-dns.com."}]},
+    {"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.com/CNAME/www", "name": "www", "fqdn": "www.example.com.", "type": "Microsoft.Network/dnszones/CNAME", "TTL": 3600, "cnameRecord": {"cname": "gone.azurewebsites.net"}},
+    {"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.com/MX/@", "name": "@", "fqdn": "example.com.", "type": "Microsoft.Network/dnszones/MX", "TTL": 3600, "mxRecords": [{"exchange": "mx.example.net", "preference": 10}]},
+    {"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.com/NS/child", "name": "child", "fqdn": "child.example.com.", "type": "Microsoft.Network/dnszones/NS", "TTL": 3600, "nsRecords": [{"nsdname": "ns.dangling-dns.net."}]},
+    {"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.com/AAAA/v6", "name": "v6", "fqdn": "v6.example.com.", "type": "Microsoft.Network/dnszones/AAAA", "TTL": 3600, "aaaaRecords": [{"ipv6Address": "2001:db8::1"}]}
+]`
+
+const gcloudFixture = `[
+    {"kind": "dns#resourceRecordSet", "name": "example.com.", "type": "NS", "ttl": 21600, "rrdatas": ["ns-cloud-a1.googledomains.com."]},
+    {"kind": "dns#resourceRecordSet", "name": "www.example.com.", "type": "CNAME", "ttl": 300, "rrdatas": ["app.herokuapp.com."]},
+    {"kind": "dns#resourceRecordSet", "name": "api.example.com.", "type": "A", "ttl": 300, "rrdatas": ["192.0.2.1", "192.0.2.2"]},
+    {"kind": "dns#resourceRecordSet", "name": "txt.example.com.", "type": "TXT", "ttl": 300, "rrdatas": ["\"hello\""]}
+]`
+
+func readProviderFixture(t *testing.T, format, data string) *zoneData {
+    t.Helper()
+
+    filename := filepath.Join(t.TempDir(), format+".export")
+    if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    zone, err := readProviderExport(filename, format)
+    if err != nil {
+        t.Fatalf("readProviderExport(%s): %v", format, err)
+    }
+    return zone
+}
+
+func TestReadProviderExports(t *testing.T) {
+    type record struct {
+        hops string
+        ips  string
+        ns   string
+        mx   string
+    }
+
+    tests := []struct {
+        format  string
+        data    string
+        apex    string
+        zoneID  string
+        targets map[string]record
+    }{
+        {
+            format: "route53",
+            data:   route53Fixture,
+            apex:   "example.com.",
+            targets: map[string]record{
+                "example.com":        {},
+                "www.example.com":    {hops: "www.example.com.>app.herokuapp.com."},
+                "assets.example.com": {hops: "assets.example.com.>assets.example.com.s3-website-us-east-1.amazonaws.com."},
+                "*.dev.example.com":  {hops: "*.dev.example.com.>dev.azurewebsites.net."},
+            },
+        },
+        {
+            format: "cloudflare",
+            data:   cloudflareAPIFixture,
+            apex:   "example.com.",
+            zoneID: "023e105f4ecef8ad9ca31a8372d0c353",
+            targets: map[string]record{
+                "www.example.com": {hops: "www.example.com.>app.herokuapp.com."},
+                "example.com":     {mx: "mx.example.net."},
+                "api.example.com": {ips: "192.0.2.1"},
+            },
+        },
+        {
+            format: "cloudflare",
+            data:   cloudflareBINDFixture,
+            apex:   "example.com.",
+            targets: map[string]record{
+                "www.example.com": {hops: "www.example.com.>app.herokuapp.com."},
+                "api.example.com": {ips: "192.0.2.1"},
+            },
+        },
+        {
+            format: "azure",
+            data:   azureFixture,
+            apex:   "example.com.",
+            zoneID: "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.com",
+            targets: map[string]record{
+                "example.com":       {mx: "mx.example.net."},
+                "www.example.com":   {hops: "www.example.com.>gone.azurewebsites.net."},
+                "child.example.com": {ns: "ns.dangling-dns.net."},
+                "v6.example.com":    {ips: "2001:db8::1"},
+            },
+        },
+        {
+            format: "gcloud",
+            data:   gcloudFixture,
+            apex:   "example.com.",
+            targets: map[string]record{
+                "example.com":     {},
+                "www.example.com": {hops: "www.example.com.>app.herokuapp.com."},
+                "api.example.com": {ips: "192.0.2.1,192.0.2.2"},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.format, func(t *testing.T) {
+            zone := readProviderFixture(t, tt.format, tt.data)
+            if zone.apex != tt.apex {
+                t.Errorf("apex = %q, want %q", zone.apex, tt.apex)
+            }
+            if zone.zoneID != tt.zoneID {
+                t.Errorf("zone id = %q, want %q", zone.zoneID, tt.zoneID)
+            }
+
+            targets := zone.targets()
+            if len(targets) != len(tt.targets) {
+                t.Errorf("got %d targets, want %d", len(targets), len(tt.targets))
+            }
+            for _, target := range targets {
+                want, ok := tt.targets[target.name]
+                if !ok {
+                    t.Errorf("unexpected target %s", target.name)
+                    continue
+                }
+                if target.zoneID != tt.zoneID {
+                    t.Errorf("%s: zone id %q, want %q", target.name, target.zoneID, tt.zoneID)
+                }
+                got := record{
+                    hops: hopChain(target.answer),
+                    ips:  ipList(target.answer),
+                    ns:   strings.Join(target.answer.nameServers, ","),
+                    mx:   strings.Join(target.answer.mx, ","),
+                }
+                if got != want {
+                    t.Errorf("%s: got %+v, want %+v", target.name, got, want)
+                }
+            }
+        })
+    }
+}
+
+func TestReadProviderExportRejectsMalformedJSON(t *testing.T) {
+    for _, format := range []string{"route53", "cloudflare", "azure", "gcloud"} {
+        filename := filepath.Join(t.TempDir(), format+".export")
+        if err := os.WriteFile(filename, []byte(`{"truncated": [`), 0o644); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := readProviderExport(filename, format); err == nil {
+            t.Errorf("%s: malformed export was accepted", format)
+        }
+    }
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+
+    "github.com/miekg/dns"
+    "golang.org/x/net/publicsuffix"
+)
+
+type DanglingRecord struct {
+    Type    string `json:"type"`
+    Value   string `json:"value"`
+    Target  string `json:"target"`
+    Reason  string `json:"reason"`
+    Service string `json:"service,omitempty"`
+    Status  string `json:"status"`
+}
+
+var recordCheckTypes = map[string]bool{
+    "mx":  true,
+    "spf": true,
+    "srv": true,
+    "txt": true,
+}
+
+var srvPrefixes = []string{
+    "_sip._tls",
+    "_sipfederationtls._tcp",
+    "_autodiscover._tcp",
+    "_xmpp-server._tcp",
+    "_xmpp-client._tcp",
+    "_caldavs._tcp",
+    "_imaps._tcp",
+    "_submission._tcp",
+}
+
+var txtHostRegex = regexp.MustCompile(`(?i)\b((?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63})\b`)
+
+func recordCheckEnabled(check string) bool {
+    for _, c := range config.RecordChecks {
+        if strings.EqualFold(c, check) || strings.EqualFold(c, "all") {
+            return true
+        }
+    }
+    return false
+}
+
+func checkRecords(target scanTarget, result *Result) {
+    subdomain := target.name
+    var records []DanglingRecord
+
+    if target.answer.hasMX() {
+        records = append(records, checkMXHosts(target.answer.mx)...)
+    } else if recordCheckEnabled("mx") {
+        records = append(records, checkMXRecords(subdomain)...)
+    }
+    if recordCheckEnabled("spf") || recordCheckEnabled("txt") {
+        records = append(records, checkTXTRecords(subdomain)...)
+    }
+    if recordCheckEnabled("srv") {
+        records = append(records, checkSRVRecords(subdomain)...)
+    }
+
+    if len(records) == 0 {
+        return
+    }
+
+    result.DanglingRecords = append(result.DanglingRecords, records...)
+
+    for _, record := range records {
+        if statusRank(record.Status) <= statusRank(result.Status) {
+            continue
+        }
+
+        result.Status = record.Status
+        result.RecordType = record.Type
+        result.Service = record.Service
+        result.Confidence = "medium"
+        if record.Status == "vulnerable" {
+            result.Confidence = "high"
+        }
+        if result.Service == "" {
+            result.Service = "Unregistered domain"
+        }
+        result.Evidence = fmt.Sprintf("Dangling %s %s -> %s (%s)", record.Type, record.Value, record.Target, record.Reason)
+    }
+}
+
+func checkMXRecords(subdomain string) []DanglingRecord {
+    resp, err := queryDNS(subdomain, dns.TypeMX)
+    if err != nil {
+        return nil
+    }
+
+    var hosts []string
+    for _, rr := range resp.Answer {
+        if mx, ok := rr.(*dns.MX); ok {
+            hosts = append(hosts, mx.Mx)
+        }
+    }
+    return checkMXHosts(hosts)
+}
+
+func checkMXHosts(hosts []string) []DanglingRecord {
+    var records []DanglingRecord
+    for _, host := range hosts {
+        if host == "." {
+            continue
+        }
+        if record, dangling := checkDanglingHost("MX", host, host); dangling {
+            records = append(records, record)
+        }
+    }
+    return records
+}
+
+func checkTXTRecords(subdomain string) []DanglingRecord {
+    resp, err := queryDNS(subdomain, dns.TypeTXT)
+    if err != nil {
+        return nil
+    }
+
+    var records []DanglingRecord
+    for _, rr := range resp.Answer {
+        txt, ok := rr.(*dns.TXT)
+        if !ok {
+            continue
+        }
+        value := strings.Join(txt.Txt, "")
+
+        if strings.HasPrefix(strings.ToLower(value), "v=spf1") {
+            if !recordCheckEnabled("spf") {
+                continue
+            }
+            for _, include := range spfIncludes(value) {
+                if record, dangling := checkDanglingHost("SPF", value, include); dangling {
+                    records = append(records, record)
+                }
+            }
+            continue
+        }
+
+        if !recordCheckEnabled("txt") {
+            continue
+        }
+        for _, host := range txtHosts(value) {
+            if record, dangling := checkDanglingHost("TXT", value, host); dangling {
+                records = append(records, record)
+            }
+        }
+    }
+    return records
+}
+
+func checkSRVRecords(subdomain string) []DanglingRecord {
+    names := []string{subdomain}
+    if !strings.HasPrefix(subdomain, "_") {
+        names = nil
+        for _, prefix := range srvPrefixes {
+            names = append(names, prefix+"."+subdomain)
+        }
+    }
+
+    var records []DanglingRecord
+    for _, name := range names {
+        resp, err := queryDNS(name, dns.TypeSRV)
+        if err != nil {
+            continue
+        }
+        for _, rr := range resp.Answer {
+            srv, ok := rr.(*dns.SRV)
+            if !ok || srv.Target == "." {
+                continue
+            }
+            value := fmt.Sprintf("%s %d %s", name, srv.Port, srv.Target)
+            if record, dangling := checkDanglingHost("SRV", value, srv.Target); dangling {
+                records = append(records, record)
+            }
+        }
+    }
+    return records
+}
+
+func spfIncludes(value string) []string {
+    var domains []string
+    for _, term := range strings.Fields(value) {
+        term = strings.TrimLeft(term, "+-~?")
+        lower := strings.ToLower(term)
+        switch {
+        case strings.HasPrefix(lower, "include:"):
+            domains = append(domains, term[len("include:"):])
+        case strings.HasPrefix(lower, "redirect="):
+            domains = append(domains, term[len("redirect="):])
+        }
+    }
+    return domains
+}
+
+func txtHosts(value string) []string {
+    var hosts []string
+    for _, match := range txtHostRegex.FindAllString(value, -1) {
+        if _, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(match)); err == nil {
+            hosts = append(hosts, match)
+        }
+    }
+    return hosts
+}
+
+func checkDanglingHost(recordType, value, host string) (DanglingRecord, bool) {
+    record := DanglingRecord{Type: recordType, Value: value, Target: dns.Fqdn(host)}
+
+    if strings.Contains(host, "%{") {
+        return record, false
+    }
+
+    hops, rcode, err := resolveCNAMEChain(host)
+    if err != nil || rcode != dns.RcodeNameError {
+        return record, false
+    }
+
+    final := dns.Fqdn(host)
+    if len(hops) > 0 {
+        final = hops[len(hops)-1].Target
+        record.Target = formatChain(hops)
+    }
+
+    matches := matchChainSignatures(host, hops)
+    if len(matches) > 0 {
+        record.Service = matches[0].signature.Service
+    }
+
+    record.Status = "potentially_vulnerable"
+    record.Reason = "NXDOMAIN"
+
+    domain, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimSuffix(strings.ToLower(final), "."))
+    if err == nil && !domainRegistered(domain) {
+        record.Status = "vulnerable"
+        record.Reason = fmt.Sprintf("domain %s is not registered", domain)
+        return record, true
+    }
+
+    for _, match := range matches {
+        if match.signature.NXDomain {
+            record.Status = "vulnerable"
+            record.Reason = "NXDOMAIN on claimable service"
+            break
+        }
+    }
+
+    return record, true
+}
+
+func domainRegistered(domain string) bool {
+    resp, err := queryDNS(domain, dns.TypeNS)
+    if err != nil {
+        return true
+    }
+    return resp.Rcode != dns.RcodeNameError
+}
+
+func statusRank(status string) int {
+    switch status {
+    case "vulnerable":
+        return 2
+    case "potentially_vulnerable":
+        return 1
+    }
+    return 0
+}
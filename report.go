@@ -0,0 +1,425 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "text/template"
+    "time"
+
+    "github.com/miekg/dns"
+    "golang.org/x/net/publicsuffix"
+)
+
+const defaultReportTemplate = `# Subdomain takeover on {{.Subdomain}} ({{.Service}})
+
+## Summary
+
+` + "`{{.Subdomain}}`" + ` points at {{.Service}}{{if .Target}} (` + "`{{.Target}}`" + `){{end}} through its {{.RecordType}} record, but the resource behind it is no longer claimed. Anyone can claim it on {{.Service}} and serve their own content on ` + "`{{.Subdomain}}`" + `.
+
+## Affected asset
+
+* Host: ` + "`{{.Subdomain}}`" + `
+* Record: {{.RecordType}}{{if .Target}} -> ` + "`{{.Target}}`" + `{{end}}
+* Service: {{.Service}}
+* Confidence: {{.Confidence}}
+{{- if .ZoneID}}
+* Zone: ` + "`{{.ZoneID}}`" + `{{end}}
+{{- if .Resource}}
+* Resource: ` + "`{{.Resource}}`" + `{{end}}
+{{if .CNAMEChain}}
+## CNAME chain
+
+` + "```" + `
+{{range .CNAMEChain}}{{fqdn .Name}} {{.TTL}} IN CNAME {{fqdn .Target}}
+{{end}}` + "```" + `
+{{end}}
+## Steps to reproduce
+
+{{range .Steps}}1. {{.}}
+{{end}}
+## Impact
+
+{{.Impact}}
+
+## Evidence
+
+` + "```" + `
+{{.Excerpt}}
+` + "```" + `
+{{if .References}}
+## References
+
+{{range .References}}* {{.}}
+{{end}}{{end}}
+_Found by SubTake on {{.Date}}._
+`
+
+var reportFilenameRegex = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+var takeoverSteps = map[string][]string{
+    "GitHub Pages": {
+        "Create a new GitHub repository and enable GitHub Pages on it.",
+        "Add a file named `CNAME` to the published branch containing `{host}`.",
+        "Add an `index.html` with a harmless proof-of-concept message.",
+    },
+    "Heroku": {
+        "Create a new Heroku app: `heroku create`.",
+        "Add the custom domain: `heroku domains:add {host}`.",
+        "Deploy a page with a harmless proof-of-concept message.",
+    },
+    "AWS S3": {
+        "Create an S3 bucket named `{host}` in the region the endpoint `{target}` belongs to.",
+        "Enable static website hosting on the bucket.",
+        "Upload an `index.html` with a harmless proof-of-concept message and make it publicly readable.",
+    },
+    "AWS Elastic Beanstalk": {
+        "Create an Elastic Beanstalk environment in the same region with the CNAME prefix from `{target}`.",
+        "Deploy an application that serves a harmless proof-of-concept message.",
+    },
+    "Azure": {
+        "Create an Azure resource (App Service, Storage, CDN endpoint, ...) with the name used in `{target}`.",
+        "Add `{host}` as a custom domain on the resource.",
+        "Serve a harmless proof-of-concept message from it.",
+    },
+    "Azure Cloud Services": {
+        "Create a cloud service with the name used in `{target}`.",
+        "Deploy a package that serves a harmless proof-of-concept message.",
+    },
+    "Azure Traffic Manager": {
+        "Create a Traffic Manager profile with the DNS name used in `{target}`.",
+        "Add an endpoint you control that serves a harmless proof-of-concept message.",
+    },
+    "Netlify": {
+        "Create a new Netlify site and deploy a harmless proof-of-concept page.",
+        "Under Domain settings, add `{host}` as a custom domain.",
+    },
+    "Shopify": {
+        "Create a Shopify store.",
+        "Under Online Store > Domains, connect the existing domain `{host}`.",
+    },
+    "Fastly": {
+        "Create a Fastly service with a backend you control.",
+        "Add `{host}` as a domain on the service and activate it.",
+    },
+    "Surge.sh": {
+        "Create a folder with an `index.html` containing a harmless proof-of-concept message.",
+        "Publish it with `surge . {host}`.",
+    },
+    "Tumblr": {
+        "Create a Tumblr blog.",
+        "Under the blog settings, enable a custom domain and set it to `{host}`.",
+    },
+    "Zendesk": {
+        "Create a Zendesk account with the subdomain used in `{target}`.",
+        "Set `{host}` as the host mapping of the help center.",
+    },
+    "Bitbucket": {
+        "Create a Bitbucket account or workspace named after the first label of `{target}`.",
+        "Create the repository `<workspace>.bitbucket.io` with an `index.html` containing a harmless proof-of-concept message.",
+    },
+    "Ghost.io": {
+        "Create a Ghost(Pro) site with the subdomain used in `{target}`.",
+        "Add `{host}` as the custom domain of the site.",
+    },
+    "Pantheon": {
+        "Create a Pantheon site and deploy a harmless proof-of-concept page.",
+        "Add `{host}` as a custom domain in the site dashboard.",
+    },
+    "Readme.io": {
+        "Create a ReadMe project.",
+        "Set `{host}` as the custom domain of the project.",
+    },
+    "Help Scout": {
+        "Create a Help Scout Docs site.",
+        "Set `{host}` as the custom domain of the site.",
+    },
+    "Unbounce": {
+        "Create an Unbounce landing page.",
+        "Add `{host}` as a custom domain and publish the page on it.",
+    },
+    "WordPress.com": {
+        "Create a WordPress.com site.",
+        "Add `{host}` as a domain under the site's domain settings.",
+    },
+    "Statuspage": {
+        "Create a Statuspage page.",
+        "Set `{host}` as the custom domain of the page.",
+    },
+    "Intercom": {
+        "Create an Intercom Help Center.",
+        "Set `{host}` as the custom domain of the Help Center.",
+    },
+    "Freshdesk": {
+        "Create a Freshdesk account with the subdomain used in `{target}`.",
+        "Set `{host}` as the helpdesk URL.",
+    },
+    "Unregistered domain": {
+        "Register the expired domain that the record points at (`{target}`) with any registrar.",
+        "Point it at a server you control and serve a harmless proof-of-concept message.",
+    },
+}
+
+var nsTakeoverSteps = []string{
+    "Create a hosted zone for `{host}` with {service}.",
+    "Repeat until the zone is assigned one of the delegated name servers ({nameservers}).",
+    "Add an A or TXT record to the zone as a proof of concept and query it: `dig @{nameserver} {host} TXT`.",
+}
+
+var registerDomainStep = "Register `{domain}`, the unregistered domain behind `{target}`, with any registrar."
+
+var danglingRecordSteps = map[string]struct{ claim, confirm string }{
+    "MX": {
+        claim:   "Add an A record for `{target}` pointing at a mail server you control.",
+        confirm: "Confirm `dig {host} MX` still lists `{lookup}`, then send a test mail to `postmaster@{host}` and check it is delivered to your server.",
+    },
+    "SPF": {
+        claim:   "Publish `v=spf1 ip4:<your mail server> -all` as a TXT record on `{target}`.",
+        confirm: "Confirm the SPF lookup reaches your record: `dig {lookup} TXT` returns it. Mail sent from your server as `{host}` now passes SPF.",
+    },
+    "SRV": {
+        claim:   "Add an A record for `{target}` pointing at a server you control.",
+        confirm: "Confirm the service lookup leads to your server: `dig {srvname} SRV` returns `{lookup}` and `dig {target} A` returns your address.",
+    },
+    "TXT": {
+        claim:   "Add an A record for `{target}` pointing at a server you control and serve a harmless proof-of-concept message.",
+        confirm: "Confirm `dig {target} A` returns your address and `http://{target}` serves the proof-of-concept message.",
+    },
+}
+
+var genericTakeoverSteps = []string{
+    "Create an account on {service} and claim the resource `{target}` points at.",
+    "Add `{host}` as a custom domain on the resource.",
+    "Serve a harmless proof-of-concept message from it.",
+}
+
+type reportData struct {
+    Result
+    Target     string
+    Steps      []string
+    Impact     string
+    Excerpt    string
+    References []string
+    Date       string
+}
+
+type reportWriter struct {
+    dir       string
+    templates map[string]*template.Template
+}
+
+func newReportWriter(dir, templateDir string) (*reportWriter, error) {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, fmt.Errorf("creating report directory: %v", err)
+    }
+
+    w := &reportWriter{dir: dir, templates: make(map[string]*template.Template)}
+    w.templates["report"] = template.Must(newReportTemplate("report").Parse(defaultReportTemplate))
+
+    if templateDir == "" {
+        return w, nil
+    }
+
+    files, err := filepath.Glob(filepath.Join(templateDir, "*.md.tmpl"))
+    if err != nil {
+        return nil, err
+    }
+    if len(files) == 0 {
+        return nil, fmt.Errorf("no *.md.tmpl files in %s", templateDir)
+    }
+
+    for _, file := range files {
+        data, err := os.ReadFile(file)
+        if err != nil {
+            return nil, fmt.Errorf("reading report template: %v", err)
+        }
+
+        name := strings.TrimSuffix(filepath.Base(file), ".md.tmpl")
+        tmpl, err := newReportTemplate(name).Parse(string(data))
+        if err != nil {
+            return nil, fmt.Errorf("parsing report template %s: %v", file, err)
+        }
+        w.templates[name] = tmpl
+    }
+
+    return w, nil
+}
+
+func newReportTemplate(name string) *template.Template {
+    return template.New(name).Funcs(template.FuncMap{
+        "fqdn": dns.Fqdn,
+        "join": strings.Join,
+    })
+}
+
+func (w *reportWriter) Write(result Result) error {
+    if result.Status != "vulnerable" {
+        return nil
+    }
+
+    tmpl, ok := w.templates[serviceSlug(result.Service)]
+    if !ok {
+        tmpl = w.templates["report"]
+    }
+
+    name := strings.Trim(reportFilenameRegex.ReplaceAllString(strings.Replace(strings.ToLower(result.Subdomain), "*", "wildcard", 1), "_"), ".")
+    file, err := os.Create(filepath.Join(w.dir, name+".md"))
+    if err != nil {
+        return err
+    }
+
+    buffered := bufio.NewWriter(file)
+    if err := tmpl.Execute(buffered, newReportData(result)); err != nil {
+        file.Close()
+        return err
+    }
+
+    return closeBuffered(buffered, file)
+}
+
+func (w *reportWriter) Flush() error {
+    return nil
+}
+
+func (w *reportWriter) Close() error {
+    return nil
+}
+
+func newReportData(result Result) reportData {
+    data := reportData{
+        Result:  result,
+        Impact:  reportImpact(result),
+        Excerpt: result.Evidence,
+        Date:    time.Now().Format("2006-01-02"),
+    }
+    if result.Snippet != "" {
+        data.Excerpt = result.Evidence + "\n\n" + result.Snippet
+    }
+    if data.RecordType == "" {
+        data.RecordType = "CNAME"
+    }
+
+    for _, signature := range signatures {
+        if signature.Service == result.Service {
+            data.References = signature.References
+            break
+        }
+    }
+
+    data.Target = result.CNAME
+    switch result.RecordType {
+    case "NS":
+        data.Target = strings.Join(result.NameServers, ", ")
+    case "A", "AAAA":
+        data.Target = result.IP
+    }
+
+    record, dangling := reportDanglingRecord(result)
+    lookup, host, domain, srvName := data.Target, data.Target, data.Target, ""
+    if dangling {
+        data.Target = record.Target
+        chain := strings.Split(record.Target, " -> ")
+        lookup = strings.TrimSuffix(chain[0], ".")
+        host = strings.TrimSuffix(chain[len(chain)-1], ".")
+        domain = host
+        if registered, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+            domain = registered
+        }
+        if fields := strings.Fields(record.Value); record.Type == "SRV" && len(fields) > 0 {
+            srvName = fields[0]
+        }
+    }
+
+    nameServer := "<name server>"
+    if len(result.NameServers) > 0 {
+        nameServer = result.NameServers[0]
+    }
+    replacer := strings.NewReplacer(
+        "{host}", result.Subdomain,
+        "{target}", host,
+        "{lookup}", lookup,
+        "{domain}", domain,
+        "{srvname}", srvName,
+        "{service}", result.Service,
+        "{nameservers}", strings.Join(result.NameServers, ", "),
+        "{nameserver}", nameServer,
+    )
+
+    steps, ok := takeoverSteps[result.Service]
+    switch {
+    case result.RecordType == "NS":
+        steps = nsTakeoverSteps
+    case dangling && result.Service == "Unregistered domain":
+        extra := danglingRecordSteps[record.Type]
+        steps = []string{registerDomainStep, extra.claim, extra.confirm}
+    case dangling:
+        if !ok {
+            steps = genericTakeoverSteps[:2]
+        }
+        steps = append(append([]string(nil), steps...), danglingRecordSteps[record.Type].confirm)
+    case !ok:
+        steps = genericTakeoverSteps
+    }
+
+    data.Steps = append(data.Steps, reportCheckStep(result))
+    for _, step := range steps {
+        data.Steps = append(data.Steps, replacer.Replace(step))
+    }
+    if _, ok := danglingRecordSteps[result.RecordType]; !ok && result.RecordType != "NS" {
+        data.Steps = append(data.Steps, fmt.Sprintf("Browse to `http://%s` and confirm the proof-of-concept message is served.", strings.Replace(result.Subdomain, "*", "poc", 1)))
+    }
+    data.Steps = append(data.Steps, "Release the claimed resource once the finding has been confirmed.")
+
+    return data
+}
+
+func reportDanglingRecord(result Result) (DanglingRecord, bool) {
+    if _, ok := danglingRecordSteps[result.RecordType]; !ok {
+        return DanglingRecord{}, false
+    }
+
+    for _, record := range result.DanglingRecords {
+        if record.Type == result.RecordType && record.Status == result.Status {
+            return record, true
+        }
+    }
+    for _, record := range result.DanglingRecords {
+        if record.Type == result.RecordType {
+            return record, true
+        }
+    }
+    return DanglingRecord{}, false
+}
+
+func reportCheckStep(result Result) string {
+    switch result.RecordType {
+    case "NS":
+        return fmt.Sprintf("Confirm the delegation is lame: `dig %s NS` lists name servers that answer SERVFAIL or REFUSED for the zone.", result.Subdomain)
+    case "MX", "SPF", "SRV", "TXT":
+        name := result.Subdomain
+        if record, ok := reportDanglingRecord(result); ok && record.Type == "SRV" {
+            name = strings.Fields(record.Value)[0]
+        }
+        return fmt.Sprintf("Confirm the dangling record: `dig %s %s`.", name, strings.Replace(result.RecordType, "SPF", "TXT", 1))
+    case "A", "AAAA":
+        return fmt.Sprintf("Confirm the record: `dig %s %s` returns `%s`.", result.Subdomain, result.RecordType, result.IP)
+    }
+    return fmt.Sprintf("Confirm the record: `dig %s CNAME` returns `%s`.", result.Subdomain, result.CNAME)
+}
+
+func reportImpact(result Result) string {
+    switch result.RecordType {
+    case "NS":
+        return fmt.Sprintf("An attacker who claims the zone controls every record under `%s`: they can serve any content, receive mail and obtain TLS certificates for the name and all its subdomains.", result.Subdomain)
+    case "MX":
+        return fmt.Sprintf("An attacker who claims the mail host receives mail sent to `%s`, including password resets and verification mails.", result.Subdomain)
+    case "SPF":
+        return fmt.Sprintf("An attacker who claims the included domain decides which servers may send mail as `%s`: spoofed mail from them passes SPF and, with an aligned envelope sender, DMARC.", result.Subdomain)
+    case "SRV":
+        return fmt.Sprintf("An attacker who claims the service host receives the connections clients make for `%s`, such as SIP, XMPP or Autodiscover logins, and can capture the credentials sent to it.", result.Subdomain)
+    }
+    return fmt.Sprintf("An attacker who claims the resource serves arbitrary content on `%s`. This allows convincing phishing pages, reading cookies scoped to the parent domain, bypassing CORS or CSP allow-lists that trust the parent domain, and obtaining TLS certificates for the name.", result.Subdomain)
+}
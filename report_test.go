@@ -0,0 +1,89 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestReportStepsForDanglingRecords(t *testing.T) {
+    tests := []struct {
+        name    string
+        result  Result
+        want    []string
+        exclude []string
+    }{
+        {
+            name: "spf include on an unregistered domain",
+            result: Result{
+                Subdomain: "example.com", Status: "vulnerable", RecordType: "SPF", Service: "Unregistered domain",
+                DanglingRecords: []DanglingRecord{
+                    {Type: "SPF", Value: "v=spf1 include:_spf.expired-mailer.com -all", Target: "_spf.expired-mailer.com.", Status: "vulnerable"},
+                },
+            },
+            want: []string{
+                "`dig example.com TXT`",
+                "Register `expired-mailer.com`",
+                "as a TXT record on `_spf.expired-mailer.com`",
+                "`dig _spf.expired-mailer.com TXT`",
+            },
+            exclude: []string{"Browse to"},
+        },
+        {
+            name: "srv target behind a cname chain",
+            result: Result{
+                Subdomain: "example.com", Status: "vulnerable", RecordType: "SRV", Service: "Unregistered domain",
+                DanglingRecords: []DanglingRecord{
+                    {Type: "MX", Value: "mx.example.net.", Target: "mx.example.net.", Status: "potentially_vulnerable"},
+                    {Type: "SRV", Value: "_sip._tls.example.com 443 sip.example.com", Target: "sip.example.com. -> sip.old-voip.net.", Status: "vulnerable"},
+                },
+            },
+            want: []string{
+                "`dig _sip._tls.example.com SRV`.",
+                "Register `old-voip.net`",
+                "Add an A record for `sip.old-voip.net`",
+                "`dig _sip._tls.example.com SRV` returns `sip.example.com` and `dig sip.old-voip.net A`",
+            },
+            exclude: []string{"Browse to", "mx.example.net"},
+        },
+        {
+            name: "mx host on a claimable service",
+            result: Result{
+                Subdomain: "mail.example.com", Status: "potentially_vulnerable", RecordType: "MX", Service: "Heroku",
+                DanglingRecords: []DanglingRecord{
+                    {Type: "MX", Value: "gone.herokuapp.com.", Target: "gone.herokuapp.com.", Status: "potentially_vulnerable", Service: "Heroku"},
+                },
+            },
+            want: []string{
+                "`dig mail.example.com MX`.",
+                "postmaster@mail.example.com",
+            },
+            exclude: []string{"Browse to", "Register `"},
+        },
+        {
+            name: "cname keeps the browse step",
+            result: Result{
+                Subdomain: "www.example.com", Status: "vulnerable", Service: "Surge.sh", CNAME: "na-west1.surge.sh",
+            },
+            want: []string{
+                "`surge . www.example.com`",
+                "Browse to `http://www.example.com`",
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            steps := strings.Join(newReportData(tt.result).Steps, "\n")
+            for _, want := range tt.want {
+                if !strings.Contains(steps, want) {
+                    t.Errorf("steps do not contain %q:\n%s", want, steps)
+                }
+            }
+            for _, exclude := range tt.exclude {
+                if strings.Contains(steps, exclude) {
+                    t.Errorf("steps contain %q:\n%s", exclude, steps)
+                }
+            }
+        })
+    }
+}
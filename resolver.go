@@ -0,0 +1,259 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "net"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/fatih/color"
+    "github.com/miekg/dns"
+)
+
+const maxResolverMismatches = 3
+
+type resolverPool struct {
+    servers []*dnsServer
+    trusted *dnsServer
+    retries int
+    udp     *dns.Client
+    tcp     *dns.Client
+    next    uint32
+}
+
+type dnsServer struct {
+    addr       string
+    limiter    *rateLimiter
+    mismatches int32
+    disabled   int32
+}
+
+type rateLimiter struct {
+    mu       sync.Mutex
+    interval time.Duration
+    next     time.Time
+}
+
+func newResolverPool(addrs []string, trusted string, rate, retries int, timeout time.Duration) (*resolverPool, error) {
+    if len(addrs) == 0 {
+        return nil, fmt.Errorf("no resolvers configured")
+    }
+
+    pool := &resolverPool{
+        retries: retries,
+        udp:     &dns.Client{Net: "udp", Timeout: timeout},
+        tcp:     &dns.Client{Net: "tcp", Timeout: timeout},
+    }
+
+    for _, addr := range addrs {
+        normalized, err := normalizeResolver(addr)
+        if err != nil {
+            return nil, err
+        }
+        pool.servers = append(pool.servers, &dnsServer{addr: normalized, limiter: newRateLimiter(rate)})
+    }
+
+    if trusted != "" {
+        normalized, err := normalizeResolver(trusted)
+        if err != nil {
+            return nil, fmt.Errorf("trusted resolver: %v", err)
+        }
+        pool.trusted = &dnsServer{addr: normalized, limiter: newRateLimiter(rate)}
+    }
+
+    return pool, nil
+}
+
+func normalizeResolver(addr string) (string, error) {
+    addr = strings.TrimSpace(addr)
+    if host, port, err := net.SplitHostPort(addr); err == nil {
+        if net.ParseIP(host) == nil {
+            return "", fmt.Errorf("invalid resolver address %q", addr)
+        }
+        return net.JoinHostPort(host, port), nil
+    }
+
+    if net.ParseIP(strings.Trim(addr, "[]")) == nil {
+        return "", fmt.Errorf("invalid resolver address %q", addr)
+    }
+    return net.JoinHostPort(strings.Trim(addr, "[]"), "53"), nil
+}
+
+func readResolversFile(filename string) ([]string, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, fmt.Errorf("opening resolvers file: %v", err)
+    }
+    defer file.Close()
+
+    var resolvers []string
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if i := strings.Index(line, "#"); i >= 0 {
+            line = strings.TrimSpace(line[:i])
+        }
+        if line != "" {
+            resolvers = append(resolvers, line)
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("reading resolvers file: %v", err)
+    }
+
+    return resolvers, nil
+}
+
+func systemResolvers() []string {
+    var resolvers []string
+    if cfg, err := dns.ClientConfigFromFile("/etc/resolv.conf"); err == nil {
+        for _, server := range cfg.Servers {
+            resolvers = append(resolvers, net.JoinHostPort(server, cfg.Port))
+        }
+    }
+    if len(resolvers) == 0 {
+        resolvers = []string{"8.8.8.8:53", "1.1.1.1:53"}
+    }
+    return resolvers
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+    if perSecond <= 0 {
+        return nil
+    }
+    return &rateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+func (l *rateLimiter) wait() {
+    if l == nil {
+        return
+    }
+
+    l.mu.Lock()
+    now := time.Now()
+    if l.next.Before(now) {
+        l.next = now
+    }
+    delay := l.next.Sub(now)
+    l.next = l.next.Add(l.interval)
+    l.mu.Unlock()
+
+    time.Sleep(delay)
+}
+
+func (p *resolverPool) pick() *dnsServer {
+    for i := 0; i < len(p.servers); i++ {
+        n := atomic.AddUint32(&p.next, 1)
+        server := p.servers[int(n)%len(p.servers)]
+        if atomic.LoadInt32(&server.disabled) == 0 {
+            return server
+        }
+    }
+    if p.trusted != nil {
+        return p.trusted
+    }
+    return p.servers[int(atomic.AddUint32(&p.next, 1))%len(p.servers)]
+}
+
+func (p *resolverPool) exchange(msg *dns.Msg, server *dnsServer) (*dns.Msg, error) {
+    server.limiter.wait()
+
+    resp, _, err := p.udp.Exchange(msg, server.addr)
+    if err == nil && resp.Truncated {
+        resp, _, err = p.tcp.Exchange(msg, server.addr)
+    }
+    return resp, err
+}
+
+func (p *resolverPool) Query(name string, qtype uint16) (*dns.Msg, error) {
+    msg := new(dns.Msg)
+    msg.SetQuestion(dns.Fqdn(name), qtype)
+    msg.RecursionDesired = true
+    msg.SetEdns0(4096, false)
+
+    var lastErr error
+    for attempt := 0; attempt <= p.retries; attempt++ {
+        server := p.pick()
+
+        resp, err := p.exchange(msg, server)
+        if err != nil {
+            lastErr = fmt.Errorf("%s: %v", server.addr, err)
+            continue
+        }
+        if resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused {
+            lastErr = fmt.Errorf("%s from %s", dns.RcodeToString[resp.Rcode], server.addr)
+            continue
+        }
+
+        if p.trusted != nil && server != p.trusted && needsCrossCheck(resp) {
+            return p.crossCheck(msg, resp, server), nil
+        }
+        return resp, nil
+    }
+
+    if p.trusted != nil {
+        resp, err := p.exchange(msg, p.trusted)
+        if err == nil && resp.Rcode != dns.RcodeServerFailure && resp.Rcode != dns.RcodeRefused {
+            return resp, nil
+        }
+    }
+
+    return nil, lastErr
+}
+
+func (p *resolverPool) Direct(addr, name string, qtype uint16) (*dns.Msg, error) {
+    msg := new(dns.Msg)
+    msg.SetQuestion(dns.Fqdn(name), qtype)
+    msg.RecursionDesired = false
+    msg.SetEdns0(4096, false)
+
+    return p.exchange(msg, &dnsServer{addr: addr})
+}
+
+func needsCrossCheck(resp *dns.Msg) bool {
+    if resp.Rcode == dns.RcodeNameError {
+        return true
+    }
+    for _, rr := range resp.Answer {
+        if rr.Header().Rrtype == dns.TypeCNAME {
+            return true
+        }
+    }
+    return false
+}
+
+func (p *resolverPool) crossCheck(msg, resp *dns.Msg, server *dnsServer) *dns.Msg {
+    trusted, err := p.exchange(msg, p.trusted)
+    if err != nil || trusted.Rcode == dns.RcodeServerFailure || trusted.Rcode == dns.RcodeRefused {
+        return resp
+    }
+
+    if trusted.Rcode == resp.Rcode && answerKey(trusted) == answerKey(resp) {
+        return resp
+    }
+
+    if atomic.AddInt32(&server.mismatches, 1) >= maxResolverMismatches {
+        if atomic.CompareAndSwapInt32(&server.disabled, 0, 1) {
+            color.Yellow("[~] Disabling resolver %s: answers disagree with trusted resolver", server.addr)
+        }
+    }
+
+    return trusted
+}
+
+func answerKey(resp *dns.Msg) string {
+    var records []string
+    for _, rr := range resp.Answer {
+        if cname, ok := rr.(*dns.CNAME); ok {
+            records = append(records, strings.ToLower(cname.Hdr.Name)+">"+strings.ToLower(cname.Target))
+        }
+    }
+    sort.Strings(records)
+    return strings.Join(records, ",")
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+    "net"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/miekg/dns"
+)
+
+func startDNSServer(t *testing.T, handler dns.HandlerFunc, withTCP bool) string {
+    t.Helper()
+
+    pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listening on udp: %v", err)
+    }
+    addr := pc.LocalAddr().String()
+    serve(t, &dns.Server{PacketConn: pc, Handler: handler})
+
+    if withTCP {
+        l, err := net.Listen("tcp", addr)
+        if err != nil {
+            t.Fatalf("listening on tcp %s: %v", addr, err)
+        }
+        serve(t, &dns.Server{Listener: l, Handler: handler})
+    }
+
+    return addr
+}
+
+func serve(t *testing.T, server *dns.Server) {
+    t.Helper()
+
+    started := make(chan struct{})
+    server.NotifyStartedFunc = func() { close(started) }
+    go server.ActivateAndServe()
+    t.Cleanup(func() { server.Shutdown() })
+
+    select {
+    case <-started:
+    case <-time.After(2 * time.Second):
+        t.Fatal("dns server did not start")
+    }
+}
+
+func answerWith(rcode int, records ...string) dns.HandlerFunc {
+    return func(w dns.ResponseWriter, r *dns.Msg) {
+        m := new(dns.Msg)
+        m.SetRcode(r, rcode)
+        for _, record := range records {
+            rr, err := dns.NewRR(record)
+            if err != nil {
+                panic(err)
+            }
+            m.Answer = append(m.Answer, rr)
+        }
+        w.WriteMsg(m)
+    }
+}
+
+func counting(count *int32, next dns.HandlerFunc) dns.HandlerFunc {
+    return func(w dns.ResponseWriter, r *dns.Msg) {
+        atomic.AddInt32(count, 1)
+        next(w, r)
+    }
+}
+
+func newTestPool(t *testing.T, addrs []string, trusted string, rate, retries int) *resolverPool {
+    t.Helper()
+
+    pool, err := newResolverPool(addrs, trusted, rate, retries, time.Second)
+    if err != nil {
+        t.Fatalf("newResolverPool: %v", err)
+    }
+    return pool
+}
+
+func TestResolverPoolRotates(t *testing.T) {
+    var first, second int32
+    ok := answerWith(dns.RcodeSuccess, "example.com. 60 IN A 192.0.2.1")
+    pool := newTestPool(t, []string{
+        startDNSServer(t, counting(&first, ok), false),
+        startDNSServer(t, counting(&second, ok), false),
+    }, "", 0, 0)
+
+    for i := 0; i < 6; i++ {
+        if _, err := pool.Query("example.com", dns.TypeA); err != nil {
+            t.Fatalf("query %d: %v", i, err)
+        }
+    }
+
+    if f, s := atomic.LoadInt32(&first), atomic.LoadInt32(&second); f != 3 || s != 3 {
+        t.Errorf("queries per resolver = %d, %d; want 3, 3", f, s)
+    }
+}
+
+func TestResolverPoolRetriesServfailAndRefused(t *testing.T) {
+    var servfail, refused, good int32
+    pool := newTestPool(t, []string{
+        startDNSServer(t, counting(&good, answerWith(dns.RcodeSuccess, "example.com. 60 IN A 192.0.2.1")), false),
+        startDNSServer(t, counting(&servfail, answerWith(dns.RcodeServerFailure)), false),
+        startDNSServer(t, counting(&refused, answerWith(dns.RcodeRefused)), false),
+    }, "", 0, 2)
+
+    resp, err := pool.Query("example.com", dns.TypeA)
+    if err != nil {
+        t.Fatalf("Query: %v", err)
+    }
+    if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+        t.Errorf("got rcode %s with %d answers, want NOERROR with 1", dns.RcodeToString[resp.Rcode], len(resp.Answer))
+    }
+    sf, rf, gd := atomic.LoadInt32(&servfail), atomic.LoadInt32(&refused), atomic.LoadInt32(&good)
+    if sf != 1 || rf != 1 || gd != 1 {
+        t.Errorf("queries = servfail %d, refused %d, good %d; want 1 each", sf, rf, gd)
+    }
+}
+
+func TestResolverPoolGivesUpAfterRetries(t *testing.T) {
+    pool := newTestPool(t, []string{
+        startDNSServer(t, answerWith(dns.RcodeServerFailure), false),
+    }, "", 0, 1)
+
+    if _, err := pool.Query("example.com", dns.TypeA); err == nil {
+        t.Error("expected an error when every attempt returns SERVFAIL")
+    }
+}
+
+func TestResolverPoolFallsBackToTCP(t *testing.T) {
+    var udp, tcp int32
+    addr := startDNSServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+        if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+            atomic.AddInt32(&tcp, 1)
+            answerWith(dns.RcodeSuccess, "example.com. 60 IN A 192.0.2.1", "example.com. 60 IN A 192.0.2.2")(w, r)
+            return
+        }
+
+        atomic.AddInt32(&udp, 1)
+        m := new(dns.Msg)
+        m.SetReply(r)
+        m.Truncated = true
+        w.WriteMsg(m)
+    }, true)
+
+    pool := newTestPool(t, []string{addr}, "", 0, 0)
+    resp, err := pool.Query("example.com", dns.TypeA)
+    if err != nil {
+        t.Fatalf("Query: %v", err)
+    }
+
+    if u, c := atomic.LoadInt32(&udp), atomic.LoadInt32(&tcp); u != 1 || c != 1 {
+        t.Errorf("queries = udp %d, tcp %d; want 1, 1", u, c)
+    }
+    if resp.Truncated || len(resp.Answer) != 2 {
+        t.Errorf("got truncated=%v with %d answers, want the full TCP answer", resp.Truncated, len(resp.Answer))
+    }
+}
+
+func TestResolverPoolDisablesResolverDisagreeingWithTrusted(t *testing.T) {
+    var liar int32
+    liarAddr := startDNSServer(t, counting(&liar, answerWith(dns.RcodeNameError)), false)
+    honestAddr := startDNSServer(t, answerWith(dns.RcodeSuccess, "www.example.com. 60 IN CNAME app.example.net."), false)
+    trustedAddr := startDNSServer(t, answerWith(dns.RcodeSuccess, "www.example.com. 60 IN CNAME app.example.net."), false)
+
+    pool := newTestPool(t, []string{liarAddr, honestAddr}, trustedAddr, 0, 0)
+
+    for i := 0; i < 2*maxResolverMismatches; i++ {
+        resp, err := pool.Query("www.example.com", dns.TypeCNAME)
+        if err != nil {
+            t.Fatalf("query %d: %v", i, err)
+        }
+        if resp.Rcode != dns.RcodeSuccess {
+            t.Fatalf("query %d: got %s, want the trusted NOERROR answer", i, dns.RcodeToString[resp.Rcode])
+        }
+    }
+
+    var disabled *dnsServer
+    for _, server := range pool.servers {
+        if server.addr == liarAddr {
+            disabled = server
+        }
+    }
+    if atomic.LoadInt32(&disabled.disabled) != 1 {
+        t.Fatalf("resolver %s was not disabled after %d mismatches", liarAddr, atomic.LoadInt32(&disabled.mismatches))
+    }
+
+    before := atomic.LoadInt32(&liar)
+    for i := 0; i < 4; i++ {
+        if _, err := pool.Query("www.example.com", dns.TypeCNAME); err != nil {
+            t.Fatalf("query after disabling: %v", err)
+        }
+    }
+    if after := atomic.LoadInt32(&liar); after != before {
+        t.Errorf("disabled resolver still received %d queries", after-before)
+    }
+}
+
+func TestResolverPoolRateLimitsPerResolver(t *testing.T) {
+    const rate = 20
+    const queries = 6
+
+    addr := startDNSServer(t, answerWith(dns.RcodeSuccess, "example.com. 60 IN A 192.0.2.1"), false)
+    pool := newTestPool(t, []string{addr}, "", rate, 0)
+
+    var wg sync.WaitGroup
+    start := time.Now()
+    for i := 0; i < queries; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if _, err := pool.Query("example.com", dns.TypeA); err != nil {
+                t.Error(err)
+            }
+        }()
+    }
+    wg.Wait()
+
+    want := time.Duration(queries-1) * time.Second / rate
+    if elapsed := time.Since(start); elapsed < want {
+        t.Errorf("%d queries at %d/s took %v, want at least %v", queries, rate, elapsed, want)
+    }
+}
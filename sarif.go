@@ -0,0 +1,248 @@
+package main
+
+import (
+    "bufio"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+)
+
+const (
+    sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+    sarifVersion = "2.1.0"
+)
+
+type sarifLog struct {
+    Schema  string     `json:"$schema"`
+    Version string     `json:"version"`
+    Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+    Tool    sarifTool     `json:"tool"`
+    Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+    Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+    Name           string      `json:"name"`
+    Version        string      `json:"version"`
+    InformationURI string      `json:"informationUri"`
+    Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+    ID                   string                 `json:"id"`
+    Name                 string                 `json:"name"`
+    ShortDescription     sarifMessage           `json:"shortDescription"`
+    FullDescription      sarifMessage           `json:"fullDescription"`
+    HelpURI              string                 `json:"helpUri,omitempty"`
+    DefaultConfiguration sarifConfiguration     `json:"defaultConfiguration"`
+    Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifConfiguration struct {
+    Level string `json:"level"`
+}
+
+type sarifMessage struct {
+    Text string `json:"text"`
+}
+
+type sarifResult struct {
+    RuleID              string                 `json:"ruleId"`
+    RuleIndex           int                    `json:"ruleIndex"`
+    Level               string                 `json:"level"`
+    Message             sarifMessage           `json:"message"`
+    Locations           []sarifLocation        `json:"locations"`
+    PartialFingerprints map[string]string      `json:"partialFingerprints"`
+    Properties          map[string]interface{} `json:"properties"`
+}
+
+type sarifLocation struct {
+    PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+    ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+    Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+    URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+    StartLine int `json:"startLine"`
+}
+
+type sarifWriter struct {
+    file    *os.File
+    rules   []sarifRule
+    index   map[string]int
+    results []sarifResult
+}
+
+func newSarifWriter(file *os.File) *sarifWriter {
+    w := &sarifWriter{file: file, index: make(map[string]int)}
+    for _, signature := range signatures {
+        w.addRule(signature.Service, signature)
+    }
+    return w
+}
+
+func (w *sarifWriter) addRule(service string, signature ServiceSignature) int {
+    id := sarifRuleID(service)
+    if i, ok := w.index[id]; ok {
+        return i
+    }
+
+    confidence := signature.Confidence
+    if confidence == "" {
+        confidence = "medium"
+    }
+
+    rule := sarifRule{
+        ID:                   id,
+        Name:                 sarifRuleName(service),
+        ShortDescription:     sarifMessage{Text: fmt.Sprintf("Subdomain takeover: %s", service)},
+        FullDescription:      sarifMessage{Text: fmt.Sprintf("A DNS record points at an unclaimed %s resource that anyone can register.", service)},
+        DefaultConfiguration: sarifConfiguration{Level: sarifLevel(confidence)},
+        Properties: map[string]interface{}{
+            "tags":       []string{"security", "subdomain-takeover"},
+            "confidence": confidence,
+        },
+    }
+    if len(signature.CNAMES) > 0 {
+        rule.Properties["cnames"] = signature.CNAMES
+    }
+    if len(signature.References) > 0 {
+        rule.HelpURI = signature.References[0]
+    }
+
+    w.index[id] = len(w.rules)
+    w.rules = append(w.rules, rule)
+    return w.index[id]
+}
+
+func (w *sarifWriter) Write(result Result) error {
+    if result.Status != "vulnerable" && result.Status != "potentially_vulnerable" {
+        return nil
+    }
+
+    service := result.Service
+    if service == "" {
+        service = "Unknown"
+    }
+    ruleIndex := w.addRule(service, ServiceSignature{Confidence: result.Confidence})
+    ruleID := w.rules[ruleIndex].ID
+
+    properties := map[string]interface{}{
+        "status":     result.Status,
+        "confidence": result.Confidence,
+        "evidence":   result.Evidence,
+    }
+    for key, value := range map[string]string{
+        "cname":          result.CNAME,
+        "ip":             result.IP,
+        "record_type":    result.RecordType,
+        "cloud_provider": result.CloudProvider,
+        "cloud_region":   result.CloudRegion,
+        "zone_id":        result.ZoneID,
+        "resource":       result.Resource,
+    } {
+        if value != "" {
+            properties[key] = value
+        }
+    }
+    if len(result.CNAMEChain) > 0 {
+        properties["cname_chain"] = result.CNAMEChain
+    }
+    if len(result.NameServers) > 0 {
+        properties["nameservers"] = result.NameServers
+    }
+    if len(result.DanglingRecords) > 0 {
+        properties["dangling_records"] = result.DanglingRecords
+    }
+
+    fingerprint := sha256.Sum256([]byte(strings.Join([]string{ruleID, strings.ToLower(result.Subdomain), result.RecordType}, "|")))
+
+    w.results = append(w.results, sarifResult{
+        RuleID:    ruleID,
+        RuleIndex: ruleIndex,
+        Level:     sarifLevel(result.Confidence),
+        Message:   sarifMessage{Text: fmt.Sprintf("%s -> %s (%s): %s", result.Subdomain, resultTarget(result), service, result.Evidence)},
+        Locations: []sarifLocation{{
+            PhysicalLocation: sarifPhysicalLocation{
+                ArtifactLocation: sarifArtifactLocation{URI: result.Subdomain},
+                Region:           sarifRegion{StartLine: 1},
+            },
+        }},
+        PartialFingerprints: map[string]string{"subtakeFinding/v1": hex.EncodeToString(fingerprint[:])},
+        Properties:          properties,
+    })
+    return nil
+}
+
+func (w *sarifWriter) Flush() error {
+    return nil
+}
+
+func (w *sarifWriter) Close() error {
+    log := sarifLog{
+        Schema:  sarifSchema,
+        Version: sarifVersion,
+        Runs: []sarifRun{{
+            Tool: sarifTool{Driver: sarifDriver{
+                Name:           "SubTake",
+                Version:        "2.0",
+                InformationURI: "https://github.com/monsifhmouri/SubTake",
+                Rules:          w.rules,
+            }},
+            Results: w.results,
+        }},
+    }
+    if log.Runs[0].Results == nil {
+        log.Runs[0].Results = []sarifResult{}
+    }
+
+    buffered := bufio.NewWriter(w.file)
+    encoder := json.NewEncoder(buffered)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(log); err != nil {
+        w.file.Close()
+        return err
+    }
+    return closeBuffered(buffered, w.file)
+}
+
+func sarifRuleID(service string) string {
+    return "takeover/" + serviceSlug(service)
+}
+
+func sarifRuleName(service string) string {
+    var name strings.Builder
+    for _, word := range strings.Split(serviceSlug(service), "-") {
+        if word == "" {
+            continue
+        }
+        name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+    }
+    return name.String()
+}
+
+func sarifLevel(confidence string) string {
+    switch confidence {
+    case "high":
+        return "error"
+    case "low":
+        return "note"
+    }
+    return "warning"
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "github.com/fatih/color"
+    "gopkg.in/yaml.v3"
+)
+
+const builtinSource = "built-in"
+
+type signatureFile struct {
+    Signatures []ServiceSignature `json:"signatures"`
+}
+
+func loadCustomSignatures(paths []string, replace bool) error {
+    var external []ServiceSignature
+    seen := make(map[string]string)
+
+    for _, path := range paths {
+        files, err := collectFiles(path, ".json", ".yaml", ".yml")
+        if err != nil {
+            return fmt.Errorf("signature source %s: %v", path, err)
+        }
+
+        for _, file := range files {
+            loaded, err := parseSignatureFile(file)
+            if err != nil {
+                return err
+            }
+
+            for i, sig := range loaded {
+                if err := validateSignature(sig); err != nil {
+                    return fmt.Errorf("%s: signature #%d: %v", file, i+1, err)
+                }
+
+                key := strings.ToLower(sig.Service)
+                if previous, ok := seen[key]; ok {
+                    return fmt.Errorf("%s: duplicate service %q (already defined in %s)", file, sig.Service, previous)
+                }
+                seen[key] = file
+
+                sig.Source = file
+                external = append(external, sig)
+            }
+        }
+    }
+
+    signatures = mergeSignatures(signatures, external, replace)
+    return nil
+}
+
+func parseSignatureFile(path string) ([]ServiceSignature, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading signature file: %v", err)
+    }
+
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        var doc interface{}
+        if err := yaml.Unmarshal(data, &doc); err != nil {
+            return nil, fmt.Errorf("parsing %s: %v", path, err)
+        }
+        data, err = json.Marshal(doc)
+        if err != nil {
+            return nil, fmt.Errorf("parsing %s: %v", path, err)
+        }
+    }
+
+    data = bytes.TrimSpace(data)
+    if len(data) == 0 {
+        return nil, nil
+    }
+
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+
+    if data[0] == '[' {
+        var list []ServiceSignature
+        if err := decoder.Decode(&list); err != nil {
+            return nil, fmt.Errorf("parsing %s: %v", path, err)
+        }
+        return list, nil
+    }
+
+    var file signatureFile
+    if err := decoder.Decode(&file); err != nil {
+        return nil, fmt.Errorf("parsing %s: %v", path, err)
+    }
+    return file.Signatures, nil
+}
+
+func validateSignature(sig ServiceSignature) error {
+    if strings.TrimSpace(sig.Service) == "" {
+        return fmt.Errorf("service is required")
+    }
+    if len(sig.CNAMES) == 0 && len(sig.NS) == 0 {
+        return fmt.Errorf("service %q: at least one cname or ns pattern is required", sig.Service)
+    }
+    for _, ns := range sig.NS {
+        if strings.TrimSpace(ns) == "" {
+            return fmt.Errorf("service %q: empty ns pattern", sig.Service)
+        }
+    }
+    for _, cname := range sig.CNAMES {
+        if strings.TrimSpace(cname) == "" {
+            return fmt.Errorf("service %q: empty cname pattern", sig.Service)
+        }
+    }
+    switch sig.Confidence {
+    case "high", "medium", "low":
+    default:
+        return fmt.Errorf("service %q: confidence must be high, medium or low (got %q)", sig.Service, sig.Confidence)
+    }
+    if sig.StatusCode != 0 && (sig.StatusCode < 100 || sig.StatusCode > 599) {
+        return fmt.Errorf("service %q: invalid status_code %d", sig.Service, sig.StatusCode)
+    }
+    if sig.BodyMatch != "" {
+        if _, err := regexp.Compile(sig.BodyMatch); err != nil {
+            return fmt.Errorf("service %q: invalid body_match regex: %v", sig.Service, err)
+        }
+    }
+    if sig.BodyExclude != "" {
+        if _, err := regexp.Compile(sig.BodyExclude); err != nil {
+            return fmt.Errorf("service %q: invalid body_exclude regex: %v", sig.Service, err)
+        }
+    }
+    if sig.StatusCode == 0 && sig.BodyMatch == "" && sig.HeaderMatch == "" && len(sig.Matchers) == 0 && !sig.NXDomain && len(sig.NS) == 0 {
+        return fmt.Errorf("service %q: one of matchers, status_code, body_match, header_match, nxdomain or ns is required", sig.Service)
+    }
+    if err := compileSignature(&sig); err != nil {
+        return fmt.Errorf("service %q: %v", sig.Service, err)
+    }
+
+    return nil
+}
+
+func mergeSignatures(builtin, external []ServiceSignature, replace bool) []ServiceSignature {
+    if replace {
+        return external
+    }
+
+    overrides := make(map[string]bool)
+    for _, sig := range external {
+        overrides[strings.ToLower(sig.Service)] = true
+    }
+
+    var merged []ServiceSignature
+    for _, sig := range builtin {
+        if overrides[strings.ToLower(sig.Service)] {
+            color.Yellow("[~] Built-in signature %q overridden by external definition", sig.Service)
+            continue
+        }
+        merged = append(merged, sig)
+    }
+
+    return append(merged, external...)
+}
+
+func listSignatures() {
+    color.Cyan("[+] %d signatures loaded", len(signatures))
+    for _, sig := range signatures {
+        patterns := sig.CNAMES
+        if len(patterns) == 0 {
+            patterns = sig.NS
+        }
+        fmt.Printf("%-24s %-7s %-40s %s\n",
+            sig.Service, sig.Confidence, strings.Join(patterns, ","), sig.Source)
+    }
+}
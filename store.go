@@ -0,0 +1,390 @@
+package main
+
+import (
+    "database/sql"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "github.com/fatih/color"
+    _ "modernc.org/sqlite"
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at  TEXT NOT NULL,
+    finished_at TEXT,
+    command     TEXT NOT NULL,
+    total       INTEGER NOT NULL DEFAULT 0,
+    vulnerable  INTEGER NOT NULL DEFAULT 0,
+    potential   INTEGER NOT NULL DEFAULT 0,
+    wildcard    INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS targets (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL UNIQUE,
+    first_seen TEXT NOT NULL,
+    last_seen  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS results (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    scan_id     INTEGER NOT NULL REFERENCES scans(id),
+    target_id   INTEGER NOT NULL REFERENCES targets(id),
+    checked_at  TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    service     TEXT,
+    confidence  TEXT,
+    record_type TEXT,
+    cname       TEXT,
+    ip          TEXT
+);
+
+CREATE TABLE IF NOT EXISTS evidence (
+    result_id   INTEGER PRIMARY KEY REFERENCES results(id),
+    evidence    TEXT,
+    snippet     TEXT,
+    http_status INTEGER,
+    cname_chain TEXT,
+    result      TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS results_scan ON results(scan_id);
+CREATE INDEX IF NOT EXISTS results_target ON results(target_id);
+CREATE INDEX IF NOT EXISTS results_status ON results(status, checked_at);
+`
+
+type sqliteWriter struct {
+    db     *sql.DB
+    tx     *sql.Tx
+    scanID int64
+    stats  scanStats
+}
+
+type storedFinding struct {
+    ScanID    int64  `json:"scan_id"`
+    CheckedAt string `json:"checked_at"`
+    Result
+}
+
+func openStore(filename string) (*sql.DB, error) {
+    db, err := sql.Open("sqlite", "file:"+filename+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+    if err != nil {
+        return nil, fmt.Errorf("opening database: %v", err)
+    }
+    if _, err := db.Exec(storeSchema); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("opening database %s: %v", filename, err)
+    }
+    return db, nil
+}
+
+func newSqliteWriter(filename string) (*sqliteWriter, error) {
+    db, err := openStore(filename)
+    if err != nil {
+        return nil, err
+    }
+
+    res, err := db.Exec(`INSERT INTO scans (started_at, command) VALUES (?, ?)`,
+        storeTime(time.Now()), strings.Join(os.Args[1:], " "))
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("recording scan: %v", err)
+    }
+
+    w := &sqliteWriter{db: db}
+    w.scanID, _ = res.LastInsertId()
+    if w.tx, err = db.Begin(); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    color.Cyan("[+] Recording scan #%d in %s", w.scanID, filename)
+    return w, nil
+}
+
+func (w *sqliteWriter) Write(result Result) error {
+    w.stats.total++
+    switch result.Status {
+    case "vulnerable":
+        w.stats.vulnerable++
+    case "potentially_vulnerable":
+        w.stats.potential++
+    case "wildcard":
+        w.stats.wildcard++
+    }
+
+    now := storeTime(time.Now())
+
+    var targetID int64
+    err := w.tx.QueryRow(`INSERT INTO targets (name, first_seen, last_seen) VALUES (?, ?, ?)
+        ON CONFLICT(name) DO UPDATE SET last_seen = excluded.last_seen RETURNING id`,
+        result.Subdomain, now, now).Scan(&targetID)
+    if err != nil {
+        return err
+    }
+
+    res, err := w.tx.Exec(`INSERT INTO results (scan_id, target_id, checked_at, status, service, confidence, record_type, cname, ip)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+        w.scanID, targetID, now, result.Status, result.Service, result.Confidence, result.RecordType, result.CNAME, result.IP)
+    if err != nil {
+        return err
+    }
+    resultID, _ := res.LastInsertId()
+
+    data, err := json.Marshal(result)
+    if err != nil {
+        return err
+    }
+    var chain []byte
+    if len(result.CNAMEChain) > 0 {
+        chain, _ = json.Marshal(result.CNAMEChain)
+    }
+
+    _, err = w.tx.Exec(`INSERT INTO evidence (result_id, evidence, snippet, http_status, cname_chain, result) VALUES (?, ?, ?, ?, ?, ?)`,
+        resultID, result.Evidence, result.Snippet, result.HTTPStatus, string(chain), string(data))
+    return err
+}
+
+func (w *sqliteWriter) Flush() error {
+    if err := w.tx.Commit(); err != nil {
+        return err
+    }
+    var err error
+    w.tx, err = w.db.Begin()
+    return err
+}
+
+func (w *sqliteWriter) Close() error {
+    defer w.db.Close()
+
+    if err := w.tx.Commit(); err != nil {
+        return err
+    }
+
+    var finished interface{}
+    if scanFinished {
+        finished = storeTime(time.Now())
+    }
+
+    _, err := w.db.Exec(`UPDATE scans SET finished_at = ?, total = ?, vulnerable = ?, potential = ?, wildcard = ? WHERE id = ?`,
+        finished, w.stats.total, w.stats.vulnerable, w.stats.potential, w.stats.wildcard, w.scanID)
+    return err
+}
+
+func storeTime(t time.Time) string {
+    return t.UTC().Format(time.RFC3339)
+}
+
+func runDBCommand(args []string) {
+    if len(args) == 0 {
+        color.Red("[-] Error: usage: subtake db <list-scans|findings> [flags]")
+        os.Exit(1)
+    }
+
+    fs := flag.NewFlagSet("db "+args[0], flag.ExitOnError)
+    configFile := fs.String("config", "", "Path to JSON config file whose database is used without -db")
+    dbFile := fs.String("db", "", "SQLite database written by scans with -db (default from the config, else subtake.db)")
+
+    var err error
+    switch args[0] {
+    case "list-scans":
+        limit := fs.Int("limit", 20, "Number of most recent scans to show (0 = all)")
+        fs.Parse(args[1:])
+        if err = resolveDatabase(dbFile, *configFile); err == nil {
+            err = listScans(*dbFile, *limit)
+        }
+    case "findings":
+        service := fs.String("service", "", "Only findings for this service")
+        since := fs.String("since", "", "Only findings newer than this (e.g. 24h, 7d, 2w or 2024-01-31)")
+        status := fs.String("status", "vulnerable,potentially_vulnerable", "Comma-separated statuses to show (or all)")
+        scanID := fs.Int64("scan", 0, "Only findings from this scan ID")
+        jsonOutput := fs.Bool("json", false, "Output in JSON format")
+        fs.Parse(args[1:])
+        if err = resolveDatabase(dbFile, *configFile); err == nil {
+            err = listFindings(*dbFile, *service, *since, *status, *scanID, *jsonOutput)
+        }
+    default:
+        err = fmt.Errorf("unknown db command %q (use list-scans or findings)", args[0])
+    }
+
+    if err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+}
+
+func resolveDatabase(dbFile *string, configFile string) error {
+    if *dbFile != "" {
+        return nil
+    }
+
+    database, err := defaultDatabase(configFile)
+    if err != nil {
+        return err
+    }
+    *dbFile = database
+    return nil
+}
+
+func defaultDatabase(configFile string) (string, error) {
+    cfg := defaultConfig()
+    if configFile == "" {
+        configFile = os.Getenv("SUBTAKE_CONFIG")
+    }
+    if configFile != "" {
+        if err := loadConfigFile(&cfg, configFile); err != nil {
+            return "", err
+        }
+    }
+    if err := applyEnvConfig(&cfg); err != nil {
+        return "", err
+    }
+    if cfg.Database != "" {
+        return cfg.Database, nil
+    }
+    return "subtake.db", nil
+}
+
+func openExistingStore(filename string) (*sql.DB, error) {
+    if _, err := os.Stat(filename); err != nil {
+        return nil, fmt.Errorf("opening database: %v", err)
+    }
+    return openStore(filename)
+}
+
+func listScans(filename string, limit int) error {
+    db, err := openExistingStore(filename)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    query := `SELECT id, started_at, COALESCE(finished_at, ''), total, vulnerable, potential, wildcard, command FROM scans ORDER BY id DESC`
+    if limit > 0 {
+        query += " LIMIT " + strconv.Itoa(limit)
+    }
+    rows, err := db.Query(query)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(tw, "ID\tSTARTED\tDURATION\tTARGETS\tVULNERABLE\tPOTENTIAL\tWILDCARD\tCOMMAND")
+    for rows.Next() {
+        var id int64
+        var started, finished, command string
+        var total, vulnerable, potential, wildcard int
+        if err := rows.Scan(&id, &started, &finished, &total, &vulnerable, &potential, &wildcard, &command); err != nil {
+            return err
+        }
+
+        duration := "unfinished"
+        start, _ := time.Parse(time.RFC3339, started)
+        if end, err := time.Parse(time.RFC3339, finished); err == nil {
+            duration = end.Sub(start).String()
+        }
+        fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+            id, start.Local().Format("2006-01-02 15:04"), duration, total, vulnerable, potential, wildcard, command)
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    return tw.Flush()
+}
+
+func listFindings(filename, service, since, status string, scanID int64, jsonOutput bool) error {
+    db, err := openExistingStore(filename)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    var where []string
+    var args []interface{}
+    if service != "" {
+        where = append(where, "r.service = ? COLLATE NOCASE")
+        args = append(args, service)
+    }
+    if since != "" {
+        cutoff, err := parseSince(since)
+        if err != nil {
+            return err
+        }
+        where = append(where, "r.checked_at >= ?")
+        args = append(args, storeTime(cutoff))
+    }
+    if statuses := splitList(status); len(statuses) > 0 && status != "all" {
+        where = append(where, "r.status IN (?"+strings.Repeat(", ?", len(statuses)-1)+")")
+        for _, s := range statuses {
+            args = append(args, s)
+        }
+    }
+    if scanID != 0 {
+        where = append(where, "r.scan_id = ?")
+        args = append(args, scanID)
+    }
+
+    query := `SELECT r.scan_id, r.checked_at, e.result FROM results r JOIN evidence e ON e.result_id = r.id`
+    if len(where) > 0 {
+        query += " WHERE " + strings.Join(where, " AND ")
+    }
+    query += " ORDER BY r.checked_at, r.id"
+
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    findings := []storedFinding{}
+    for rows.Next() {
+        var finding storedFinding
+        var data string
+        if err := rows.Scan(&finding.ScanID, &finding.CheckedAt, &data); err != nil {
+            return err
+        }
+        if err := json.Unmarshal([]byte(data), &finding.Result); err != nil {
+            return fmt.Errorf("decoding result: %v", err)
+        }
+
+        if !jsonOutput {
+            checked, _ := time.Parse(time.RFC3339, finding.CheckedAt)
+            fmt.Printf("#%d %s %s\n", finding.ScanID, checked.Local().Format("2006-01-02 15:04"), formatResult(finding.Result))
+            continue
+        }
+        findings = append(findings, finding)
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    if jsonOutput {
+        jsonData, _ := json.MarshalIndent(findings, "", "  ")
+        fmt.Println(string(jsonData))
+    }
+    return nil
+}
+
+func parseSince(value string) (time.Time, error) {
+    for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+        if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) {
+            return time.Now().Add(-time.Duration(n) * unit), nil
+        }
+    }
+    if d, err := time.ParseDuration(value); err == nil {
+        return time.Now().Add(-d), nil
+    }
+    if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+        return t, nil
+    }
+    return time.Time{}, fmt.Errorf("invalid -since value %q (use e.g. 24h, 7d, 2w or 2024-01-31)", value)
+}
@@ -1,20 +1,17 @@
 package main
 
 import (
-    "bufio"
-    "crypto/tls"
     "encoding/json"
     "flag"
     "fmt"
-    "net"
     "net/http"
     "os"
-    "regexp"
     "strings"
     "sync"
     "time"
 
     "github.com/fatih/color"
+    "github.com/miekg/dns"
     "github.com/valyala/fasthttp"
 )
 
@@ -27,27 +24,60 @@ type Config struct {
     DeepCheck         bool     `json:"deep_check"`
     OutputFile        string   `json:"output_file"`
     CustomSignatures  []string `json:"custom_signatures"`
+    ReplaceSignatures bool     `json:"replace_signatures"`
+    ResolversFile     string   `json:"resolvers_file"`
+    TrustedResolver   string   `json:"trusted_resolver"`
+    ResolverRate      int      `json:"resolver_rate"`
+    DNSRetries        int      `json:"dns_retries"`
+    DNSTimeout        int      `json:"dns_timeout"`
+    NSCheck           bool     `json:"ns_check"`
+    RecordChecks      []string `json:"record_checks"`
+    CloudIPCheck      bool     `json:"cloud_ip_check"`
+    CloudRangesDir    string   `json:"cloud_ranges_dir"`
+    WildcardCheck     bool     `json:"wildcard_check"`
+    Database          string   `json:"database"`
 }
 
 type Result struct {
-    Subdomain    string `json:"subdomain"`
-    CNAME        string `json:"cname"`
-    Service      string `json:"service"`
-    Status       string `json:"status"`
-    Confidence   string `json:"confidence"`
-    Evidence     string `json:"evidence"`
-    IP           string `json:"ip"`
-    ResponseTime int64  `json:"response_time"`
+    Subdomain       string           `json:"subdomain"`
+    CNAME           string           `json:"cname"`
+    Service         string           `json:"service"`
+    Status          string           `json:"status"`
+    Confidence      string           `json:"confidence"`
+    Evidence        string           `json:"evidence"`
+    IP              string           `json:"ip"`
+    ResponseTime    int64            `json:"response_time"`
+    CNAMEChain      []CNAMEHop       `json:"cname_chain,omitempty"`
+    Dangling        bool             `json:"dangling,omitempty"`
+    NameServers     []string         `json:"nameservers,omitempty"`
+    RecordType      string           `json:"record_type,omitempty"`
+    DanglingRecords []DanglingRecord `json:"dangling_records,omitempty"`
+    CloudProvider   string           `json:"cloud_provider,omitempty"`
+    CloudRegion     string           `json:"cloud_region,omitempty"`
+    ZoneID          string           `json:"zone_id,omitempty"`
+    Resource        string           `json:"resource,omitempty"`
+    HTTPStatus      int              `json:"http_status,omitempty"`
+    Snippet         string           `json:"snippet,omitempty"`
 }
 
 type ServiceSignature struct {
-    Service     string   `json:"service"`
-    CNAMES      []string `json:"cnames"`
-    Fingerprint string   `json:"fingerprint"`
-    StatusCode  int      `json:"status_code"`
-    BodyMatch   string   `json:"body_match"`
-    HeaderMatch string   `json:"header_match"`
-    Confidence  string   `json:"confidence"`
+    Service           string    `json:"service"`
+    CNAMES            []string  `json:"cnames"`
+    Fingerprint       string    `json:"fingerprint"`
+    StatusCode        int       `json:"status_code"`
+    BodyMatch         string    `json:"body_match"`
+    BodyExclude       string    `json:"body_exclude,omitempty"`
+    HeaderMatch       string    `json:"header_match"`
+    Confidence        string    `json:"confidence"`
+    NXDomain          bool      `json:"nxdomain,omitempty"`
+    References        []string  `json:"references,omitempty"`
+    NS                []string  `json:"ns,omitempty"`
+    Matchers          []Matcher `json:"matchers,omitempty"`
+    MatchersCondition string    `json:"matchers_condition,omitempty"`
+    Source            string    `json:"-"`
+
+    compiled []Matcher
+    exclude  *Matcher
 }
 
 var (
@@ -65,33 +95,7 @@ var (
 )
 
 func init() {
-    
-    config = Config{
-        Threads:         50,
-        Timeout:         10,
-        UserAgent:       "SubTake/v2.0",
-        FollowRedirects: true,
-        VerifySSL:       false,
-        DeepCheck:       true,
-        OutputFile:      "",
-    }
-
-    
-    client = &fasthttp.Client{
-        ReadTimeout:                   time.Duration(config.Timeout) * time.Second,
-        WriteTimeout:                  time.Duration(config.Timeout) * time.Second,
-        MaxConnsPerHost:               100,
-        TLSConfig:                     &tls.Config{InsecureSkipVerify: !config.VerifySSL},
-        DisableHeaderNamesNormalizing: true,
-    }
-
-    httpClient = &http.Client{
-        Timeout: time.Duration(config.Timeout) * time.Second,
-        Transport: &http.Transport{
-            TLSClientConfig: &tls.Config{InsecureSkipVerify: !config.VerifySSL},
-        },
-    }
-
+    config = defaultConfig()
     loadSignatures()
 }
 
@@ -105,6 +109,7 @@ func loadSignatures() {
             StatusCode:  404,
             BodyMatch:   "NoSuchBucket|No Such Bucket",
             Confidence:  "high",
+            NXDomain:    true,
         },
         {
             Service:    "GitHub Pages",
@@ -145,6 +150,21 @@ func loadSignatures() {
             StatusCode:  404,
             BodyMatch:   "Microsoft Azure|Azure",
             Confidence:  "medium",
+            NXDomain:    true,
+        },
+        {
+            Service:    "Azure Cloud Services",
+            CNAMES:     []string{".cloudapp.net"},
+            Fingerprint: "NXDOMAIN",
+            Confidence:  "high",
+            NXDomain:    true,
+        },
+        {
+            Service:    "Azure Traffic Manager",
+            CNAMES:     []string{".trafficmanager.net"},
+            Fingerprint: "NXDOMAIN",
+            Confidence:  "high",
+            NXDomain:    true,
         },
         {
             Service:    "Google Cloud",
@@ -178,6 +198,7 @@ func loadSignatures() {
             StatusCode:  404,
             BodyMatch:   "AWS Elastic Beanstalk",
             Confidence:  "medium",
+            NXDomain:    true,
         },
         {
             Service:    "Bitbucket",
@@ -411,50 +432,379 @@ func loadSignatures() {
             BodyMatch:   "Agile CRM|Sorry, this page is no longer available",
             Confidence:  "high",
         },
+        {
+            Service:    "AWS Route53",
+            NS:          []string{"awsdns-"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "high",
+        },
+        {
+            Service:    "Azure DNS",
+            NS:          []string{".azure-dns."},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "high",
+        },
+        {
+            Service:    "DigitalOcean DNS",
+            NS:          []string{".digitalocean.com"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "high",
+        },
+        {
+            Service:    "NS1",
+            NS:          []string{".nsone.net"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "high",
+        },
+        {
+            Service:    "Google Cloud DNS",
+            NS:          []string{".googledomains.com"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "medium",
+        },
+        {
+            Service:    "Linode DNS",
+            NS:          []string{".linode.com"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "medium",
+        },
+        {
+            Service:    "DNSimple",
+            NS:          []string{".dnsimple.com"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "medium",
+        },
+        {
+            Service:    "Hurricane Electric DNS",
+            NS:          []string{".he.net"},
+            Fingerprint: "REFUSED/SERVFAIL from delegated name servers",
+            Confidence:  "medium",
+        },
+    }
+
+    for i := range signatures {
+        signatures[i].Source = builtinSource
     }
 }
 
 func main() {
-    var targetFile, singleTarget, outputFile string
+    var targetFile, singleTarget, outputFile, configFile, userAgent, signatureSources string
     var threads, timeout int
-    var verbose, verifySSL, deepCheck, jsonOutput bool
+    var verbose, verifySSL, deepCheck, jsonOutput, followRedirects bool
+    var replaceSignatures, listSigs, printReportTemplate bool
+    var importFile, diffFile, nucleiPath, nucleiCNAMEPath string
+    var resolversFile, trustedResolver string
+    var resolverRate, dnsRetries, dnsTimeout int
+    var nsCheck bool
+    var recordChecks string
+    var cloudIPCheck, updateRanges bool
+    var cloudRangesDir string
+    var wildcardCheck bool
+    var database string
+    var inputFormat string
+    var zoneFile, zoneOrigin, axfr, zoneID string
+    var terraformFile, kubernetesPath string
+    var checkpointFile, csvFile, jsonlFile, textFile, sarifFile, htmlFile, reportDir string
+    var baselineFile, diffOutput string
+    var resume bool
 
-    flag.StringVar(&targetFile, "f", "", "File containing list of subdomains")
+    flag.StringVar(&configFile, "config", "", "Path to JSON config file")
+    flag.StringVar(&targetFile, "f", "", "File containing list of subdomains (- for stdin)")
+    flag.StringVar(&inputFormat, "input-format", "plain", "Format of -f input: plain, amass, subfinder, massdns, dnsx, route53, cloudflare, azure, gcloud")
+    flag.StringVar(&zoneID, "zone-id", "", "Provider zone ID attached to results of a DNS export or zone scan")
+    flag.StringVar(&zoneFile, "zone", "", "Scan the records of an RFC 1035 zone file")
+    flag.StringVar(&zoneOrigin, "zone-origin", "", "Origin for -zone when the file has no $ORIGIN/SOA")
+    flag.StringVar(&axfr, "axfr", "", "Scan the records of a zone transfer (zone@nameserver)")
+    flag.StringVar(&terraformFile, "terraform", "", "Find orphaned DNS records in a terraform.tfstate or terraform show -json file (- for stdin)")
+    flag.StringVar(&kubernetesPath, "kubernetes", "", "Scan hosts from Kubernetes manifests or kubectl get ingress,svc -A -o json dumps (file, directory or -)")
+    flag.StringVar(&checkpointFile, "checkpoint", "", "Record completed targets and their results in this file")
+    flag.BoolVar(&resume, "resume", false, "Skip targets already completed in the -checkpoint file")
     flag.StringVar(&singleTarget, "d", "", "Single target subdomain")
-    flag.StringVar(&outputFile, "o", "", "Output file to save results")
+    flag.StringVar(&outputFile, "o", "", "Output file to save results (format from extension: .json, .jsonl, .csv, .txt, .sarif, .html)")
+    flag.StringVar(&csvFile, "csv", "", "Also write results as CSV to this file")
+    flag.StringVar(&jsonlFile, "jsonl", "", "Also write results as JSON lines to this file")
+    flag.StringVar(&textFile, "txt", "", "Also write results as plain text to this file")
+    flag.StringVar(&sarifFile, "sarif", "", "Also write findings as SARIF 2.1.0 to this file")
+    flag.StringVar(&htmlFile, "html", "", "Also write a self-contained HTML report to this file")
+    flag.StringVar(&baselineFile, "baseline", "", "Compare results with an earlier scan (JSON, JSONL or CSV) and exit with status 3 on new vulnerabilities")
+    flag.StringVar(&diffOutput, "diff-output", "", "Write the -baseline comparison as JSON to this file")
+    flag.StringVar(&database, "db", "", "Record the scan and its results in this SQLite database")
+    flag.StringVar(&reportDir, "reports", "", "Write a markdown bug-bounty report for each vulnerable finding into this directory")
+    flag.StringVar(&reportTemplateDir, "report-templates", "", "Directory with report.md.tmpl and <service>.md.tmpl overrides for -reports")
+    flag.BoolVar(&printReportTemplate, "print-report-template", false, "Print the built-in report template and exit")
     flag.IntVar(&threads, "t", 50, "Number of concurrent threads")
     flag.IntVar(&timeout, "timeout", 10, "Timeout in seconds")
+    flag.StringVar(&userAgent, "ua", "SubTake/v2.0", "User-Agent header for HTTP checks")
+    flag.BoolVar(&followRedirects, "follow-redirects", true, "Follow HTTP redirects during checks")
     flag.BoolVar(&verbose, "v", false, "Verbose output")
     flag.BoolVar(&verifySSL, "ssl", false, "Verify SSL certificates")
     flag.BoolVar(&deepCheck, "deep", true, "Perform deep checking")
     flag.BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+    flag.StringVar(&signatureSources, "signatures", "", "Comma-separated signature files or directories (JSON/YAML)")
+    flag.BoolVar(&replaceSignatures, "replace-signatures", false, "Use only external signatures instead of merging with built-ins")
+    flag.BoolVar(&listSigs, "list-signatures", false, "List loaded signatures and exit")
+    flag.StringVar(&importFile, "import-fingerprints", "", "Convert a can-i-take-over-xyz fingerprints.json into a signature file (written to -o or stdout)")
+    flag.StringVar(&diffFile, "diff-fingerprints", "", "Diff a can-i-take-over-xyz fingerprints.json against the built-in signatures")
+    flag.StringVar(&resolversFile, "r", "", "File containing DNS resolvers (one per line, ip or ip:port)")
+    flag.StringVar(&trustedResolver, "trusted-resolver", "", "Trusted DNS resolver used to cross-check NXDOMAIN and CNAME answers")
+    flag.IntVar(&resolverRate, "rate", 0, "Maximum DNS queries per second per resolver (0 = unlimited)")
+    flag.IntVar(&dnsRetries, "retries", 2, "DNS retries on timeout/SERVFAIL/REFUSED")
+    flag.IntVar(&dnsTimeout, "dns-timeout", 3, "DNS query timeout in seconds")
+    flag.BoolVar(&nsCheck, "ns", false, "Check NS delegations for lame (takeover-prone) name servers")
+    flag.StringVar(&recordChecks, "records", "", "Comma-separated record checks: mx,spf,srv,txt (or all)")
+    flag.BoolVar(&cloudIPCheck, "cloud-ips", false, "Flag A/AAAA records pointing at unused AWS/GCP/Azure IPs")
+    flag.StringVar(&cloudRangesDir, "cloud-ranges", defaultCloudRangesDir(), "Directory holding the cloud provider IP range files")
+    flag.BoolVar(&updateRanges, "update-ranges", false, "Download the latest AWS/GCP/Azure IP ranges into -cloud-ranges and exit")
+    flag.BoolVar(&wildcardCheck, "wildcard", true, "Mark results that only inherit a parent zone's wildcard record as wildcard")
+    flag.StringVar(&nucleiPath, "import-nuclei", "", "Convert nuclei takeover templates (file or directory) into a signature file (written to -o or stdout)")
+    flag.StringVar(&nucleiCNAMEPath, "nuclei-cnames", "", "JSON/YAML file mapping nuclei template ids or service names to CNAME patterns")
+
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "db":
+            runDBCommand(os.Args[2:])
+            return
+        case "diff":
+            runDiffCommand(os.Args[2:])
+            return
+        }
+    }
+
     flag.Parse()
 
-    
-    config.Threads = threads
-    config.Timeout = timeout
-    config.VerifySSL = verifySSL
-    config.DeepCheck = deepCheck
-    config.OutputFile = outputFile
+    if configFile == "" {
+        configFile = os.Getenv("SUBTAKE_CONFIG")
+    }
+    if configFile != "" {
+        if err := loadConfigFile(&config, configFile); err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+    }
+
+    if err := applyEnvConfig(&config); err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+
+    flag.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "t":
+            config.Threads = threads
+        case "timeout":
+            config.Timeout = timeout
+        case "ua":
+            config.UserAgent = userAgent
+        case "follow-redirects":
+            config.FollowRedirects = followRedirects
+        case "ssl":
+            config.VerifySSL = verifySSL
+        case "deep":
+            config.DeepCheck = deepCheck
+        case "o":
+            config.OutputFile = outputFile
+        case "signatures":
+            config.CustomSignatures = splitList(signatureSources)
+        case "replace-signatures":
+            config.ReplaceSignatures = replaceSignatures
+        case "r":
+            config.ResolversFile = resolversFile
+        case "trusted-resolver":
+            config.TrustedResolver = trustedResolver
+        case "rate":
+            config.ResolverRate = resolverRate
+        case "retries":
+            config.DNSRetries = dnsRetries
+        case "dns-timeout":
+            config.DNSTimeout = dnsTimeout
+        case "ns":
+            config.NSCheck = nsCheck
+        case "records":
+            config.RecordChecks = splitList(recordChecks)
+        case "cloud-ips":
+            config.CloudIPCheck = cloudIPCheck
+        case "cloud-ranges":
+            config.CloudRangesDir = cloudRangesDir
+        case "wildcard":
+            config.WildcardCheck = wildcardCheck
+        case "db":
+            config.Database = database
+        }
+    })
+
+    if err := validateConfig(config); err != nil {
+        color.Red("[-] Invalid configuration: %v", err)
+        os.Exit(1)
+    }
+
+    if resume && checkpointFile == "" {
+        color.Red("[-] Error: -resume requires -checkpoint")
+        os.Exit(1)
+    }
+
+    if err := validateInputFormat(inputFormat); err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+
+    setupClients()
+
+    if updateRanges {
+        if err := updateCloudRanges(config.CloudRangesDir); err != nil {
+            color.Red("[-] Error updating cloud IP ranges: %v", err)
+            os.Exit(1)
+        }
+        return
+    }
+
+    if config.CloudIPCheck {
+        if err := loadCloudRanges(config.CloudRangesDir); err != nil {
+            color.Red("[-] Error loading cloud IP ranges: %v", err)
+            os.Exit(1)
+        }
+    }
+
+    if err := setupResolver(); err != nil {
+        color.Red("[-] Error setting up DNS resolvers: %v", err)
+        os.Exit(1)
+    }
+
+    if err := loadCustomSignatures(config.CustomSignatures, config.ReplaceSignatures); err != nil {
+        color.Red("[-] Error loading signatures: %v", err)
+        os.Exit(1)
+    }
+
+    if err := compileSignatures(); err != nil {
+        color.Red("[-] Error compiling signatures: %v", err)
+        os.Exit(1)
+    }
+
+    if listSigs {
+        listSignatures()
+        return
+    }
 
+    if printReportTemplate {
+        fmt.Print(defaultReportTemplate)
+        return
+    }
+
+    if importFile != "" || diffFile != "" {
+        runFingerprintImport(importFile, diffFile)
+        return
+    }
+
+    if nucleiPath != "" {
+        runNucleiImport(nucleiPath, nucleiCNAMEPath)
+        return
+    }
     
     printBanner()
 
+    var outputs []outputSpec
+    if config.OutputFile != "" {
+        outputs = append(outputs, outputSpec{config.OutputFile, outputFormat(config.OutputFile, jsonOutput)})
+    }
+    for _, output := range []outputSpec{{csvFile, "csv"}, {jsonlFile, "jsonl"}, {textFile, "text"}, {sarifFile, "sarif"}, {htmlFile, "html"}, {reportDir, "markdown"}} {
+        if output.filename != "" {
+            outputs = append(outputs, output)
+        }
+    }
+
+    if config.Database != "" {
+        outputs = append(outputs, outputSpec{config.Database, "sqlite"})
+    }
+
+    var baseline []Result
+    if baselineFile != "" {
+        var err error
+        if baseline, err = readResultsFile(baselineFile); err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+    }
+
+    keepResults = jsonOutput || baselineFile != ""
+    var err error
+    if sinks, err = openSinks(outputs); err != nil {
+        color.Red("[-] Error: %v", err)
+        os.Exit(1)
+    }
+
+    
+    if terraformFile != "" {
+        if err := runTerraformScan(terraformFile); err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+
+        scanFinished = true
+        printResults(jsonOutput)
+        sinks.Close()
+        compareBaseline(baselineFile, baseline, diffOutput)
+        return
+    }
+
     
-    if targetFile == "" && singleTarget == "" {
-        color.Red("[-] Error: Please provide either a file with -f or a single target with -d")
+    if targetFile == "" && singleTarget == "" && zoneFile == "" && axfr == "" && kubernetesPath == "" && stdinPiped() {
+        targetFile = "-"
+    }
+    if targetFile == "" && singleTarget == "" && zoneFile == "" && axfr == "" && kubernetesPath == "" {
+        color.Red("[-] Error: Please provide a file with -f (- for stdin), a single target with -d, a zone file with -zone, a zone transfer with -axfr, Kubernetes objects with -kubernetes or a Terraform state with -terraform")
         flag.Usage()
         os.Exit(1)
     }
 
-    var targets []string
+    var targets <-chan scanTarget
 
     
-    if targetFile != "" {
-        targets = readTargetsFromFile(targetFile)
-        color.Cyan("[+] Loaded %d targets from file: %s", len(targets), targetFile)
+    if kubernetesPath != "" {
+        hosts, err := readKubernetesTargets(kubernetesPath)
+        if err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+
+        targets = sliceTargets(hosts)
+        color.Cyan("[+] Loaded %d hosts from Kubernetes objects", len(hosts))
+    } else if zoneFile != "" || axfr != "" || providerFormats[inputFormat] {
+        var zone *zoneData
+        var err error
+        switch {
+        case zoneFile != "":
+            zone, err = parseZoneFile(zoneFile, zoneOrigin)
+        case axfr != "":
+            zone, err = transferZone(axfr)
+        default:
+            zone, err = readProviderExport(targetFile, inputFormat)
+        }
+        if err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+        if zoneID != "" {
+            zone.zoneID = zoneID
+        }
+
+        records := zone.targets()
+        targets = sliceTargets(records)
+        color.Cyan("[+] Loaded %d names from zone %s", len(records), zone.apex)
+    } else if targetFile != "" {
+        input, err := openTargets(targetFile)
+        if err != nil {
+            color.Red("[-] Error opening file: %v", err)
+            os.Exit(1)
+        }
+        defer input.Close()
+
+        targets = streamTargets(input, inputFormat)
+        if targetFile == "-" {
+            color.Cyan("[+] Reading targets from stdin")
+        } else {
+            color.Cyan("[+] Reading targets from file: %s", targetFile)
+        }
     } else {
-        targets = []string{singleTarget}
+        targets = streamTargets(strings.NewReader(singleTarget), "plain")
         color.Cyan("[+] Testing single target: %s", singleTarget)
     }
 
@@ -463,115 +813,230 @@ func main() {
     color.Cyan("[+] Deep Check: %v", config.DeepCheck)
     color.Cyan("[+] SSL Verification: %v", config.VerifySSL)
 
-    
-    processTargets(targets, verbose)
+    var cp *checkpoint
+    if checkpointFile != "" {
+        var previous []Result
+        var err error
+        cp, previous, err = openCheckpoint(checkpointFile, resume)
+        if err != nil {
+            color.Red("[-] Error: %v", err)
+            os.Exit(1)
+        }
+        for _, result := range previous {
+            recordResult(result)
+        }
+        if resume {
+            color.Cyan("[+] Resuming from %s: %d targets already completed", checkpointFile, len(previous))
+        }
+    }
 
     
-    printResults(jsonOutput)
+    interrupted := processTargets(targets, verbose, cp, interruptChannel())
 
-    
-    if outputFile != "" {
-        saveResults(outputFile, jsonOutput)
+    if err := cp.Close(); err != nil {
+        color.Red("[-] Error writing checkpoint file: %v", err)
     }
-}
 
-func readTargetsFromFile(filename string) []string {
-    var targets []string
-    file, err := os.Open(filename)
-    if err != nil {
-        color.Red("[-] Error opening file: %v", err)
-        os.Exit(1)
-    }
-    defer file.Close()
+    
+    scanFinished = !interrupted && targetReadErr == nil
+    printResults(jsonOutput)
+    sinks.Close()
 
-    scanner := bufio.NewScanner(file)
-    for scanner.Scan() {
-        target := strings.TrimSpace(scanner.Text())
-        if target != "" {
-            targets = append(targets, target)
+    if interrupted {
+        if checkpointFile != "" {
+            color.Yellow("[~] Scan interrupted, continue with -checkpoint %s -resume", checkpointFile)
         }
+        os.Exit(130)
     }
 
-    if err := scanner.Err(); err != nil {
-        color.Red("[-] Error reading file: %v", err)
+    if targetReadErr != nil {
+        color.Red("[-] Error: scan incomplete, targets could not be read: %v", targetReadErr)
         os.Exit(1)
     }
 
-    return targets
+    compareBaseline(baselineFile, baseline, diffOutput)
 }
 
-func processTargets(targets []string, verbose bool) {
+func processTargets(targets <-chan scanTarget, verbose bool, cp *checkpoint, stop <-chan struct{}) bool {
     var wg sync.WaitGroup
     semaphore := make(chan struct{}, config.Threads)
+    interrupted := false
 
-    color.Cyan("[+] Processing %d targets...", len(targets))
+    color.Cyan("[+] Processing targets...")
 
-    for _, target := range targets {
+    for target := range targets {
+        if cp.completed(target.name) {
+            continue
+        }
+
+        if !acquire(semaphore, stop) {
+            interrupted = true
+            break
+        }
         wg.Add(1)
-        semaphore <- struct{}{}
 
-        go func(t string) {
+        go func(t scanTarget) {
             defer wg.Done()
             defer func() { <-semaphore }()
 
             if verbose {
-                color.Yellow("[~] Checking: %s", t)
+                color.Yellow("[~] Checking: %s", t.name)
             }
 
             result := checkSubdomain(t)
             if result.Status != "" {
-                resultsLock.Lock()
-                results = append(results, result)
-                resultsLock.Unlock()
-
+                recordResult(result)
                 printResult(result)
             }
+            cp.record(t.name, result)
         }(target)
     }
 
     wg.Wait()
+    return interrupted
 }
 
-func checkSubdomain(subdomain string) Result {
+func acquire(semaphore chan struct{}, stop <-chan struct{}) bool {
+    select {
+    case <-stop:
+        return false
+    default:
+    }
+
+    select {
+    case semaphore <- struct{}{}:
+        return true
+    case <-stop:
+        return false
+    }
+}
+
+func checkSubdomain(target scanTarget) Result {
+    subdomain := target.name
     result := Result{
         Subdomain: subdomain,
         Status:    "safe",
+        ZoneID:    target.zoneID,
     }
 
     
-    cname, err := net.LookupCNAME(subdomain)
-    if err != nil {
+    if (config.NSCheck || target.answer.hasNameServers()) && checkNSDelegation(target, &result) {
+        result.RecordType = "NS"
         return result
     }
 
+    
+    checkCNAME(subdomain, target.answer, &result)
+    if result.Status != "safe" {
+        result.RecordType = "CNAME"
+    }
+
+    
+    if config.CloudIPCheck && result.Status == "safe" && !result.Dangling {
+        checkCloudIPs(target, &result)
+    }
+
+    
+    if config.WildcardCheck {
+        checkWildcard(subdomain, &result)
+    }
+
+    
+    if len(config.RecordChecks) > 0 || target.answer.hasMX() {
+        checkRecords(target, &result)
+    }
+
+    
+    if target.kube != nil {
+        checkKubernetesBackend(target, &result)
+    }
+
+    return result
+}
+
+func checkCNAME(subdomain string, answer *resolvedAnswer, result *Result) {
+    hops, rcode, err := resolveAnswer(subdomain, answer)
+    result.CNAMEChain = hops
+    if err == errCNAMELoop {
+        result.Evidence = fmt.Sprintf("CNAME loop: %s", formatChain(hops))
+        return
+    }
+    if err != nil || (len(hops) == 0 && rcode != dns.RcodeSuccess) {
+        return
+    }
+
+    cname := dns.Fqdn(subdomain)
+    if len(hops) > 0 {
+        cname = hops[len(hops)-1].Target
+    }
     result.CNAME = cname
 
+    matches := matchChainSignatures(subdomain, hops)
+
+    
+    if len(hops) > 0 && rcode == dns.RcodeNameError {
+        checkDanglingCNAME(hops, matches, result)
+        return
+    }
+
     
-    ips, err := net.LookupIP(subdomain)
-    if err == nil && len(ips) > 0 {
+    ips := targetIPs(scanTarget{name: subdomain, answer: answer})
+    if len(ips) > 0 {
         result.IP = ips[0].String()
     }
 
     
-    for _, signature := range signatures {
-        if matchesCNAME(cname, signature.CNAMES) {
-            result.Service = signature.Service
-            result.Confidence = signature.Confidence
-
-            
-            if config.DeepCheck {
-                if verifyWithHTTP(subdomain, signature, &result) {
-                    result.Status = "vulnerable"
-                    break
-                }
-            } else {
-                result.Status = "potentially_vulnerable"
-                result.Evidence = "CNAME match only"
+    for _, match := range matches {
+        signature := match.signature
+        if len(signature.compiled) == 0 {
+            continue
+        }
+
+        result.Service = signature.Service
+        result.Confidence = signature.Confidence
+
+        
+        if config.DeepCheck {
+            if verifyWithHTTP(httpHost(subdomain), signature, result) {
+                result.Status = "vulnerable"
+                result.Evidence += hopEvidence(hops, match.hop)
+                break
             }
+        } else {
+            result.Status = "potentially_vulnerable"
+            result.Evidence = "CNAME match only" + hopEvidence(hops, match.hop)
         }
     }
+}
 
-    return result
+func checkDanglingCNAME(hops []CNAMEHop, matches []signatureMatch, result *Result) {
+    last := len(hops) - 1
+    result.Dangling = true
+    result.Evidence = fmt.Sprintf("NXDOMAIN: %s", formatChain(hops))
+
+    for _, match := range matches {
+        result.Service = match.signature.Service
+        result.Confidence = match.signature.Confidence
+        result.Status = "potentially_vulnerable"
+
+        if match.hop == last && match.signature.NXDomain {
+            result.Status = "vulnerable"
+            return
+        }
+    }
+
+    if result.Service == "" {
+        result.Service = "Unknown"
+        result.Confidence = "low"
+        result.Status = "potentially_vulnerable"
+    }
+}
+
+func hopEvidence(hops []CNAMEHop, hop int) string {
+    if hop < 0 || len(hops) < 2 {
+        return ""
+    }
+    return fmt.Sprintf(" | Matched hop %d/%d: %s -> %s", hop+1, len(hops), hops[hop].Name, hops[hop].Target)
 }
 
 func matchesCNAME(cname string, patterns []string) bool {
@@ -591,6 +1056,8 @@ func verifyWithHTTP(subdomain string, signature ServiceSignature, result *Result
         fmt.Sprintf("http://%s", subdomain),
     }
 
+    followRedirects := config.FollowRedirects && !signature.hasMatcherType("redirect")
+
     for _, testURL := range urls {
         req := fasthttp.AcquireRequest()
         resp := fasthttp.AcquireResponse()
@@ -601,47 +1068,26 @@ func verifyWithHTTP(subdomain string, signature ServiceSignature, result *Result
         req.Header.SetMethod("GET")
         req.Header.SetUserAgent(config.UserAgent)
 
-        err := client.Do(req, resp)
+        var err error
+        if followRedirects {
+            err = client.DoRedirects(req, resp, maxRedirects)
+        } else {
+            err = client.Do(req, resp)
+        }
         if err != nil {
             continue
         }
 
         result.ResponseTime = time.Since(start).Milliseconds()
 
-        body := string(resp.Body())
-        statusCode := resp.StatusCode()
-
-        
-        if signature.StatusCode != 0 && statusCode == signature.StatusCode {
-            result.Evidence = fmt.Sprintf("Status: %d", statusCode)
-        }
-
-        
-        if signature.BodyMatch != "" {
-            matched, _ := regexp.MatchString(signature.BodyMatch, body)
-            if matched {
-                result.Evidence += " | Body match"
-                return true
-            }
-        }
-
-        
-        if signature.HeaderMatch != "" {
-            headers := make(map[string]string)
-            resp.Header.VisitAll(func(key, value []byte) {
-                headers[string(key)] = string(value)
-            })
-
-            for key, value := range headers {
-                if strings.Contains(key+":"+value, signature.HeaderMatch) {
-                    result.Evidence += " | Header match"
-                    return true
-                }
-            }
-        }
+        response := newHTTPResponse(resp)
+        matched, evidence := evaluateMatchers(signature, response, testURL)
 
-        
-        if signature.StatusCode != 0 && statusCode == signature.StatusCode && signature.BodyMatch == "" {
+        result.HTTPStatus = response.status
+        result.Evidence = fmt.Sprintf("Status: %d", response.status)
+        if matched {
+            result.Evidence += " | " + strings.Join(evidence, " | ")
+            result.Snippet = response.snippet(signature)
             return true
         }
     }
@@ -652,61 +1098,39 @@ func verifyWithHTTP(subdomain string, signature ServiceSignature, result *Result
 func printResult(result Result) {
     switch result.Status {
     case "vulnerable":
-        color.Red("[VULNERABLE] %s -> %s (%s) [%s] %s", 
-            result.Subdomain, result.CNAME, result.Service, result.Confidence, result.Evidence)
+        color.Red("%s", formatResult(result))
     case "potentially_vulnerable":
-        color.Yellow("[POTENTIAL] %s -> %s (%s) [%s]", 
-            result.Subdomain, result.CNAME, result.Service, result.Confidence)
+        color.Yellow("%s", formatResult(result))
     }
 }
 
-func printResults(jsonOutput bool) {
-    color.Cyan("\n[+] Scan completed!")
-    color.Cyan("[+] Total targets processed: %d", len(results))
-    
-    vulnerable := 0
-    potential := 0
-    
-    for _, result := range results {
-        if result.Status == "vulnerable" {
-            vulnerable++
-        } else if result.Status == "potentially_vulnerable" {
-            potential++
+func resultTarget(result Result) string {
+    switch result.RecordType {
+    case "NS":
+        return "NS " + strings.Join(result.NameServers, ",")
+    case "MX", "SPF", "SRV", "TXT":
+        return result.RecordType + " record"
+    case "A", "AAAA":
+        if result.IP != "" {
+            return result.RecordType + " " + result.IP
         }
     }
-    
-    color.Red("[+] Vulnerable: %d", vulnerable)
-    color.Yellow("[+] Potential: %d", potential)
-    color.Green("[+] Safe: %d", len(results)-vulnerable-potential)
-
-    if jsonOutput {
-        jsonData, _ := json.MarshalIndent(results, "", "  ")
-        fmt.Println(string(jsonData))
-    }
+    return result.CNAME
 }
 
-func saveResults(filename string, jsonOutput bool) {
-    file, err := os.Create(filename)
-    if err != nil {
-        color.Red("[-] Error creating output file: %v", err)
-        return
-    }
-    defer file.Close()
+func printResults(jsonOutput bool) {
+    color.Cyan("\n[+] Scan completed!")
+    color.Cyan("[+] Total targets processed: %d", stats.total)
+    
+    color.Red("[+] Vulnerable: %d", stats.vulnerable)
+    color.Yellow("[+] Potential: %d", stats.potential)
+    color.Blue("[+] Wildcard: %d", stats.wildcard)
+    color.Green("[+] Safe: %d", stats.total-stats.vulnerable-stats.potential-stats.wildcard)
 
     if jsonOutput {
         jsonData, _ := json.MarshalIndent(results, "", "  ")
-        file.Write(jsonData)
-    } else {
-        writer := bufio.NewWriter(file)
-        for _, result := range results {
-            line := fmt.Sprintf("%s,%s,%s,%s,%s,%s\n",
-                result.Subdomain, result.CNAME, result.Service, result.Status, result.Confidence, result.Evidence)
-            writer.WriteString(line)
-        }
-        writer.Flush()
+        fmt.Println(string(jsonData))
     }
-
-    color.Green("[+] Results saved to: %s", filename)
 }
 
 func printBanner() {
@@ -0,0 +1,437 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/url"
+    "strings"
+
+    "github.com/fatih/color"
+    "github.com/miekg/dns"
+)
+
+type tfResource struct {
+    address string
+    rtype   string
+    attrs   map[string]interface{}
+}
+
+type tfRecord struct {
+    address    string
+    name       string
+    recordType string
+    zoneID     string
+    targets    []string
+}
+
+type tfState struct {
+    Version   int `json:"version"`
+    Resources []struct {
+        Module    string `json:"module"`
+        Mode      string `json:"mode"`
+        Type      string `json:"type"`
+        Name      string `json:"name"`
+        Instances []struct {
+            IndexKey   interface{}            `json:"index_key"`
+            Attributes map[string]interface{} `json:"attributes"`
+        } `json:"instances"`
+    } `json:"resources"`
+}
+
+type tfModule struct {
+    Resources []struct {
+        Address string                 `json:"address"`
+        Mode    string                 `json:"mode"`
+        Type    string                 `json:"type"`
+        Values  map[string]interface{} `json:"values"`
+    } `json:"resources"`
+    ChildModules []tfModule `json:"child_modules"`
+}
+
+var terraformTargetAttributes = map[string][]string{
+    "aws_s3_bucket":                       {"bucket", "website_endpoint", "bucket_domain_name", "bucket_regional_domain_name"},
+    "aws_s3_bucket_website_configuration": {"bucket", "website_endpoint"},
+    "aws_lb":                              {"dns_name"},
+    "aws_alb":                             {"dns_name"},
+    "aws_elb":                             {"dns_name"},
+    "aws_cloudfront_distribution":         {"domain_name"},
+    "aws_elastic_beanstalk_environment":   {"cname", "endpoint_url"},
+    "aws_api_gateway_domain_name":         {"cloudfront_domain_name", "regional_domain_name"},
+    "aws_apigatewayv2_domain_name":        {"domain_name_configuration.target_domain_name"},
+    "aws_acm_certificate":                 {"domain_validation_options.resource_record_value"},
+    "aws_globalaccelerator_accelerator":   {"dns_name"},
+    "aws_db_instance":                     {"address"},
+    "aws_opensearch_domain":               {"endpoint"},
+    "aws_elasticsearch_domain":            {"endpoint"},
+    "aws_eip":                             {"public_ip", "public_dns"},
+    "aws_instance":                        {"public_ip", "public_dns", "ipv6_addresses"},
+    "azurerm_app_service":                 {"id", "default_site_hostname"},
+    "azurerm_linux_web_app":               {"id", "default_hostname"},
+    "azurerm_windows_web_app":             {"id", "default_hostname"},
+    "azurerm_function_app":                {"id", "default_hostname"},
+    "azurerm_linux_function_app":          {"id", "default_hostname"},
+    "azurerm_windows_function_app":        {"id", "default_hostname"},
+    "azurerm_static_site":                 {"id", "default_host_name"},
+    "azurerm_static_web_app":              {"id", "default_host_name"},
+    "azurerm_public_ip":                   {"id", "ip_address", "fqdn"},
+    "azurerm_traffic_manager_profile":     {"id", "fqdn"},
+    "azurerm_cdn_endpoint":                {"id", "fqdn"},
+    "azurerm_cdn_frontdoor_endpoint":      {"id", "host_name"},
+    "azurerm_frontdoor":                   {"id", "cname"},
+    "azurerm_storage_account":             {"id", "primary_web_host", "primary_blob_host"},
+    "google_compute_address":              {"address"},
+    "google_compute_global_address":       {"address"},
+    "google_compute_instance":             {"network_interface.access_config.nat_ip"},
+    "google_app_engine_application":       {"default_hostname"},
+    "google_storage_bucket":               {"name"},
+    "heroku_domain":                       {"cname"},
+    "digitalocean_droplet":                {"ipv4_address", "ipv6_address"},
+    "digitalocean_loadbalancer":           {"ip"},
+    "digitalocean_app":                    {"default_ingress", "live_url"},
+}
+
+type tfShow struct {
+    Values *struct {
+        RootModule tfModule `json:"root_module"`
+    } `json:"values"`
+    PlannedValues *struct {
+        RootModule tfModule `json:"root_module"`
+    } `json:"planned_values"`
+    PriorState *struct {
+        Values *struct {
+            RootModule tfModule `json:"root_module"`
+        } `json:"values"`
+    } `json:"prior_state"`
+}
+
+func runTerraformScan(filename string) error {
+    input, err := openTargets(filename)
+    if err != nil {
+        return fmt.Errorf("opening Terraform file: %v", err)
+    }
+    defer input.Close()
+
+    data, err := io.ReadAll(input)
+    if err != nil {
+        return fmt.Errorf("reading Terraform file: %v", err)
+    }
+
+    current, prior, err := parseTerraform(data)
+    if err != nil {
+        return fmt.Errorf("parsing Terraform file: %v", err)
+    }
+
+    records := terraformRecords(current)
+    color.Cyan("[+] Loaded %d DNS records from %d Terraform resources", len(records), len(current))
+
+    for _, result := range checkTerraformRecords(records, current, prior) {
+        recordResult(result)
+        printResult(result)
+    }
+    return nil
+}
+
+func parseTerraform(data []byte) ([]tfResource, []tfResource, error) {
+    var probe map[string]json.RawMessage
+    if err := json.Unmarshal(data, &probe); err != nil {
+        return nil, nil, err
+    }
+
+    if _, ok := probe["format_version"]; !ok {
+        var state tfState
+        if err := json.Unmarshal(data, &state); err != nil {
+            return nil, nil, err
+        }
+        if state.Version < 4 {
+            return nil, nil, fmt.Errorf("unsupported state version %d (need 4 or later)", state.Version)
+        }
+        return stateResources(state), nil, nil
+    }
+
+    var show tfShow
+    if err := json.Unmarshal(data, &show); err != nil {
+        return nil, nil, err
+    }
+
+    switch {
+    case show.PlannedValues != nil:
+        var prior []tfResource
+        if show.PriorState != nil && show.PriorState.Values != nil {
+            prior = moduleResources(show.PriorState.Values.RootModule)
+        }
+        return moduleResources(show.PlannedValues.RootModule), prior, nil
+    case show.Values != nil:
+        return moduleResources(show.Values.RootModule), nil, nil
+    }
+
+    return nil, nil, fmt.Errorf("no state or planned values found")
+}
+
+func stateResources(state tfState) []tfResource {
+    var resources []tfResource
+    for _, resource := range state.Resources {
+        if resource.Mode != "managed" {
+            continue
+        }
+
+        address := resource.Type + "." + resource.Name
+        if resource.Module != "" {
+            address = resource.Module + "." + address
+        }
+
+        for _, instance := range resource.Instances {
+            instanceAddress := address
+            switch key := instance.IndexKey.(type) {
+            case string:
+                instanceAddress += fmt.Sprintf("[%q]", key)
+            case float64:
+                instanceAddress += fmt.Sprintf("[%d]", int(key))
+            }
+            resources = append(resources, tfResource{address: instanceAddress, rtype: resource.Type, attrs: instance.Attributes})
+        }
+    }
+    return resources
+}
+
+func moduleResources(module tfModule) []tfResource {
+    var resources []tfResource
+    for _, resource := range module.Resources {
+        if resource.Mode != "" && resource.Mode != "managed" {
+            continue
+        }
+        resources = append(resources, tfResource{address: resource.Address, rtype: resource.Type, attrs: resource.Values})
+    }
+    for _, child := range module.ChildModules {
+        resources = append(resources, moduleResources(child)...)
+    }
+    return resources
+}
+
+func terraformRecords(resources []tfResource) []tfRecord {
+    var records []tfRecord
+
+    for _, resource := range resources {
+        record := tfRecord{address: resource.address}
+        attrs := resource.attrs
+
+        switch resource.rtype {
+        case "aws_route53_record":
+            record.name = firstString(attrs, "fqdn", "name")
+            record.recordType = tfString(attrs, "type")
+            record.zoneID = tfString(attrs, "zone_id")
+            record.targets = tfStrings(attrs["records"])
+            if alias, ok := attrs["alias"].([]interface{}); ok {
+                for _, a := range alias {
+                    if m, ok := a.(map[string]interface{}); ok {
+                        record.targets = append(record.targets, tfString(m, "name"))
+                    }
+                }
+            }
+        case "azurerm_dns_cname_record", "azurerm_dns_a_record", "azurerm_dns_aaaa_record":
+            record.name = firstString(attrs, "fqdn", "name")
+            record.recordType = strings.ToUpper(strings.TrimSuffix(strings.TrimPrefix(resource.rtype, "azurerm_dns_"), "_record"))
+            record.zoneID = tfString(attrs, "zone_name")
+            record.targets = append(tfStrings(attrs["records"]), tfString(attrs, "record"), tfString(attrs, "target_resource_id"))
+        case "google_dns_record_set":
+            record.name = tfString(attrs, "name")
+            record.recordType = tfString(attrs, "type")
+            record.zoneID = tfString(attrs, "managed_zone")
+            record.targets = tfStrings(attrs["rrdatas"])
+        case "cloudflare_record", "cloudflare_dns_record":
+            record.name = firstString(attrs, "hostname", "name")
+            record.recordType = tfString(attrs, "type")
+            record.zoneID = tfString(attrs, "zone_id")
+            record.targets = []string{firstString(attrs, "content", "value")}
+        default:
+            continue
+        }
+
+        switch strings.ToUpper(record.recordType) {
+        case "CNAME", "A", "AAAA":
+        default:
+            continue
+        }
+
+        record.recordType = strings.ToUpper(record.recordType)
+        record.name = strings.TrimSuffix(strings.ToLower(record.name), ".")
+        records = append(records, record)
+    }
+
+    return records
+}
+
+func checkTerraformRecords(records []tfRecord, current, prior []tfResource) []Result {
+    provided := terraformValues(current)
+    previous := make(map[string]string)
+    for _, resource := range prior {
+        if isDNSResource(resource.rtype) {
+            continue
+        }
+        for value := range terraformValues([]tfResource{resource}) {
+            previous[value] = resource.address
+        }
+    }
+
+    var found []Result
+    for _, record := range records {
+        result := Result{
+            Subdomain:  record.name,
+            Status:     "safe",
+            RecordType: record.recordType,
+            ZoneID:     record.zoneID,
+            Resource:   record.address,
+        }
+
+        for _, target := range record.targets {
+            target = normalizeHost(target)
+            if target == "" {
+                continue
+            }
+
+            key, service := target, "Terraform orphan"
+            if strings.HasPrefix(target, "s3-website") {
+                key, service = record.name, "AWS S3"
+            }
+            if provided[key] {
+                continue
+            }
+
+            finding := result
+            finding.CNAME = target
+            if signature, ok := matchTargetSignature(target); ok {
+                service = signature.Service
+            }
+
+            if address, ok := previous[key]; ok {
+                finding.Status = "potentially_vulnerable"
+                finding.Service = service
+                finding.Confidence = "high"
+                finding.Evidence = fmt.Sprintf("Orphaned by plan: %s is destroyed but %s still points at %s", address, record.address, target)
+                result = finding
+                break
+            }
+
+            // Keep the first state orphan; only a plan orphan outranks it.
+            if result.Status != "safe" {
+                continue
+            }
+
+            if ip := net.ParseIP(target); ip != nil {
+                if r, ok := lookupCloudRange(ip); ok {
+                    finding.IP = target
+                    finding.CloudProvider = r.provider
+                    finding.CloudRegion = r.region
+                    service = fmt.Sprintf("%s %s", r.provider, r.service)
+                }
+            }
+
+            if service != "Terraform orphan" {
+                finding.Status = "potentially_vulnerable"
+                finding.Service = service
+                finding.Confidence = "medium"
+                finding.Evidence = fmt.Sprintf("Orphaned in state: no resource provides %s for %s", target, record.address)
+                result = finding
+            }
+        }
+
+        if result.Status != "safe" {
+            found = append(found, result)
+        }
+    }
+
+    return found
+}
+
+func matchTargetSignature(target string) (ServiceSignature, bool) {
+    if net.ParseIP(target) != nil {
+        return ServiceSignature{}, false
+    }
+    for _, signature := range signatures {
+        if matchesCNAME(dns.Fqdn(target), signature.CNAMES) {
+            return signature, true
+        }
+    }
+    return ServiceSignature{}, false
+}
+
+func terraformValues(resources []tfResource) map[string]bool {
+    values := make(map[string]bool)
+
+    for _, resource := range resources {
+        for _, attr := range terraformTargetAttributes[resource.rtype] {
+            for _, value := range tfPath(resource.attrs, strings.Split(attr, ".")) {
+                if key := normalizeHost(value); key != "" {
+                    values[key] = true
+                }
+            }
+        }
+    }
+    return values
+}
+
+func tfPath(v interface{}, path []string) []string {
+    switch value := v.(type) {
+    case string:
+        if len(path) == 0 {
+            return []string{value}
+        }
+    case []interface{}:
+        var out []string
+        for _, item := range value {
+            out = append(out, tfPath(item, path)...)
+        }
+        return out
+    case map[string]interface{}:
+        if len(path) > 0 {
+            return tfPath(value[path[0]], path[1:])
+        }
+    }
+    return nil
+}
+
+func isDNSResource(rtype string) bool {
+    switch rtype {
+    case "aws_route53_record", "azurerm_dns_cname_record", "azurerm_dns_a_record", "azurerm_dns_aaaa_record",
+        "google_dns_record_set", "cloudflare_record", "cloudflare_dns_record":
+        return true
+    }
+    return false
+}
+
+func normalizeHost(value string) string {
+    value = strings.TrimSpace(value)
+    if strings.Contains(value, "://") {
+        if u, err := url.Parse(value); err == nil {
+            value = u.Hostname()
+        }
+    }
+    return strings.TrimSuffix(strings.ToLower(value), ".")
+}
+
+func tfString(attrs map[string]interface{}, key string) string {
+    s, _ := attrs[key].(string)
+    return s
+}
+
+func firstString(attrs map[string]interface{}, keys ...string) string {
+    for _, key := range keys {
+        if s := tfString(attrs, key); s != "" {
+            return s
+        }
+    }
+    return ""
+}
+
+func tfStrings(v interface{}) []string {
+    var out []string
+    if items, ok := v.([]interface{}); ok {
+        for _, item := range items {
+            if s, ok := item.(string); ok {
+                out = append(out, s)
+            }
+        }
+    }
+    return out
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+const terraformStateFixture = `{
+    "version": 4,
+    "terraform_version": "1.6.0",
+    "resources": [
+        {
+            "mode": "managed", "type": "aws_route53_record", "name": "www",
+            "instances": [{"attributes": {"fqdn": "www.example.com", "type": "CNAME", "zone_id": "Z1", "records": ["old-app.herokuapp.com"]}}]
+        },
+        {
+            "mode": "managed", "type": "aws_route53_record", "name": "multi",
+            "instances": [{"attributes": {"fqdn": "multi.example.com", "type": "CNAME", "zone_id": "Z1", "records": ["first.herokuapp.com", "second.github.io"]}}]
+        },
+        {
+            "mode": "managed", "type": "aws_route53_record", "name": "api",
+            "instances": [{"attributes": {"fqdn": "api.example.com", "type": "CNAME", "zone_id": "Z1", "records": ["live-lb-1.us-east-1.elb.amazonaws.com"]}}]
+        },
+        {
+            "mode": "managed", "type": "aws_lb", "name": "live",
+            "instances": [{"attributes": {"dns_name": "live-lb-1.us-east-1.elb.amazonaws.com"}}]
+        },
+        {
+            "mode": "managed", "type": "aws_route53_record", "name": "static",
+            "instances": [{"attributes": {"fqdn": "static.example.com", "type": "A", "zone_id": "Z1", "records": null,
+                "alias": [{"name": "s3-website-us-east-1.amazonaws.com", "zone_id": "Z3AQBSTGFYJSTF", "evaluate_target_health": false}]}}]
+        },
+        {
+            "mode": "managed", "type": "aws_s3_bucket", "name": "static",
+            "instances": [{"attributes": {"bucket": "static.example.com", "website_endpoint": "static.example.com.s3-website-us-east-1.amazonaws.com"}}]
+        },
+        {
+            "mode": "managed", "type": "aws_route53_record", "name": "assets",
+            "instances": [{"attributes": {"fqdn": "assets.example.com", "type": "A", "zone_id": "Z1", "records": null,
+                "alias": [{"name": "s3-website-us-east-1.amazonaws.com", "zone_id": "Z3AQBSTGFYJSTF", "evaluate_target_health": false}]}}]
+        },
+        {
+            "mode": "managed", "type": "aws_route53_record", "name": "txt",
+            "instances": [{"attributes": {"fqdn": "txt.example.com", "type": "TXT", "zone_id": "Z1", "records": ["v=spf1 -all"]}}]
+        },
+        {
+            "mode": "data", "type": "aws_route53_zone", "name": "main",
+            "instances": [{"attributes": {"name": "example.com"}}]
+        }
+    ]
+}`
+
+const terraformPlanFixture = `{
+    "format_version": "1.2",
+    "planned_values": {"root_module": {
+        "resources": [
+            {"address": "aws_route53_record.app", "mode": "managed", "type": "aws_route53_record",
+             "values": {"fqdn": "app.example.com", "type": "CNAME", "zone_id": "Z1", "records": ["app-env.us-east-1.elasticbeanstalk.com"]}}
+        ],
+        "child_modules": [{"resources": [
+            {"address": "module.cdn.aws_route53_record.cdn", "mode": "managed", "type": "aws_route53_record",
+             "values": {"fqdn": "cdn.example.com", "type": "CNAME", "zone_id": "Z1", "records": ["d111.cloudfront.net"]}},
+            {"address": "module.cdn.aws_cloudfront_distribution.cdn", "mode": "managed", "type": "aws_cloudfront_distribution",
+             "values": {"domain_name": "d111.cloudfront.net"}}
+        ]}]
+    }},
+    "prior_state": {"values": {"root_module": {
+        "resources": [
+            {"address": "aws_route53_record.app", "mode": "managed", "type": "aws_route53_record",
+             "values": {"fqdn": "app.example.com", "type": "CNAME", "zone_id": "Z1", "records": ["app-env.us-east-1.elasticbeanstalk.com"]}},
+            {"address": "aws_elastic_beanstalk_environment.app", "mode": "managed", "type": "aws_elastic_beanstalk_environment",
+             "values": {"cname": "app-env.us-east-1.elasticbeanstalk.com"}}
+        ]
+    }}}
+}`
+
+func checkTerraformFixture(t *testing.T, data string) map[string]Result {
+    t.Helper()
+
+    current, prior, err := parseTerraform([]byte(data))
+    if err != nil {
+        t.Fatalf("parseTerraform: %v", err)
+    }
+
+    found := make(map[string]Result)
+    for _, result := range checkTerraformRecords(terraformRecords(current), current, prior) {
+        if _, ok := found[result.Subdomain]; ok {
+            t.Errorf("%s reported more than once", result.Subdomain)
+        }
+        found[result.Subdomain] = result
+    }
+    return found
+}
+
+func TestCheckTerraformState(t *testing.T) {
+    found := checkTerraformFixture(t, terraformStateFixture)
+
+    tests := []struct {
+        name    string
+        service string
+        cname   string
+    }{
+        {"www.example.com", "Heroku", "old-app.herokuapp.com"},
+        {"multi.example.com", "Heroku", "first.herokuapp.com"},
+        {"assets.example.com", "AWS S3", "s3-website-us-east-1.amazonaws.com"},
+    }
+
+    if len(found) != len(tests) {
+        t.Errorf("got %d findings, want %d: %+v", len(found), len(tests), found)
+    }
+    for _, tt := range tests {
+        result, ok := found[tt.name]
+        if !ok {
+            t.Errorf("%s: no finding", tt.name)
+            continue
+        }
+        if result.Service != tt.service || result.CNAME != tt.cname || result.Confidence != "medium" {
+            t.Errorf("%s: got %s -> %s (%s), want %s -> %s (medium)", tt.name, result.Service, result.CNAME, result.Confidence, tt.service, tt.cname)
+        }
+        if !strings.HasPrefix(result.Evidence, "Orphaned in state") || result.ZoneID != "Z1" || result.Resource == "" {
+            t.Errorf("%s: evidence %q, zone %q, resource %q", tt.name, result.Evidence, result.ZoneID, result.Resource)
+        }
+    }
+
+    for _, safe := range []string{"api.example.com", "static.example.com", "txt.example.com"} {
+        if _, ok := found[safe]; ok {
+            t.Errorf("%s: reported although a resource still provides its target", safe)
+        }
+    }
+}
+
+func TestCheckTerraformPlanDestroy(t *testing.T) {
+    found := checkTerraformFixture(t, terraformPlanFixture)
+
+    if len(found) != 1 {
+        t.Fatalf("got %d findings, want 1: %+v", len(found), found)
+    }
+    result, ok := found["app.example.com"]
+    if !ok {
+        t.Fatalf("app.example.com not reported: %+v", found)
+    }
+    if result.Confidence != "high" || result.Resource != "aws_route53_record.app" {
+        t.Errorf("got confidence %q, resource %q; want high, aws_route53_record.app", result.Confidence, result.Resource)
+    }
+    if !strings.Contains(result.Evidence, "aws_elastic_beanstalk_environment.app is destroyed") {
+        t.Errorf("evidence %q does not name the destroyed resource", result.Evidence)
+    }
+}
+
+func TestCheckTerraformPlanOrphanOutranksStateOrphan(t *testing.T) {
+    records := []tfRecord{{
+        address:    "aws_route53_record.mixed",
+        name:       "mixed.example.com",
+        recordType: "CNAME",
+        targets:    []string{"stale.herokuapp.com", "app-env.us-east-1.elasticbeanstalk.com"},
+    }}
+    prior := []tfResource{{
+        address: "aws_elastic_beanstalk_environment.app",
+        rtype:   "aws_elastic_beanstalk_environment",
+        attrs:   map[string]interface{}{"cname": "app-env.us-east-1.elasticbeanstalk.com"},
+    }}
+
+    found := checkTerraformRecords(records, nil, prior)
+    if len(found) != 1 {
+        t.Fatalf("got %d findings, want 1", len(found))
+    }
+    if found[0].Confidence != "high" || found[0].CNAME != "app-env.us-east-1.elasticbeanstalk.com" {
+        t.Errorf("got %s (%s), want the plan orphan at high confidence", found[0].CNAME, found[0].Confidence)
+    }
+}
+
+func TestParseTerraformRejectsOldState(t *testing.T) {
+    if _, _, err := parseTerraform([]byte(`{"version": 3, "modules": []}`)); err == nil {
+        t.Error("state version 3 was accepted")
+    }
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "strings"
+    "sync"
+
+    "github.com/miekg/dns"
+    "golang.org/x/net/publicsuffix"
+)
+
+const wildcardProbes = 2
+
+type wildcardAnswer struct {
+    parent string
+    target string
+    ips    map[string]bool
+}
+
+type wildcardEntry struct {
+    once   sync.Once
+    answer *wildcardAnswer
+}
+
+var (
+    wildcardLock  sync.Mutex
+    wildcardCache = make(map[string]*wildcardEntry)
+)
+
+func checkWildcard(subdomain string, result *Result) {
+    if result.Status != "vulnerable" && result.Status != "potentially_vulnerable" {
+        return
+    }
+    if result.RecordType != "CNAME" && result.RecordType != "A" && result.RecordType != "AAAA" {
+        return
+    }
+    if strings.HasPrefix(subdomain, "*.") {
+        return
+    }
+
+    for _, parent := range parentZones(subdomain) {
+        answer := wildcardFor(parent)
+        if answer == nil {
+            continue
+        }
+        if !inheritsWildcard(result, answer) {
+            return
+        }
+
+        target := answer.target
+        if target == "" {
+            target = result.IP
+        }
+        result.Status = "wildcard"
+        result.Evidence = fmt.Sprintf("Inherited from wildcard *.%s -> %s | %s", answer.parent, target, result.Evidence)
+        return
+    }
+}
+
+func parentZones(subdomain string) []string {
+    name := strings.ToLower(strings.TrimSuffix(subdomain, "."))
+    apex, err := publicsuffix.EffectiveTLDPlusOne(name)
+    if err != nil {
+        return nil
+    }
+
+    var parents []string
+    labels := dns.SplitDomainName(name)
+    for i := 1; i < len(labels); i++ {
+        parent := strings.Join(labels[i:], ".")
+        parents = append(parents, parent)
+        if parent == apex {
+            break
+        }
+    }
+    return parents
+}
+
+func wildcardFor(parent string) *wildcardAnswer {
+    wildcardLock.Lock()
+    entry, ok := wildcardCache[parent]
+    if !ok {
+        entry = &wildcardEntry{}
+        wildcardCache[parent] = entry
+    }
+    wildcardLock.Unlock()
+
+    entry.once.Do(func() {
+        entry.answer = probeWildcard(parent)
+    })
+    return entry.answer
+}
+
+func probeWildcard(parent string) *wildcardAnswer {
+    var answer *wildcardAnswer
+
+    for i := 0; i < wildcardProbes; i++ {
+        name := randomLabel() + "." + parent
+        hops, _, err := resolveCNAMEChain(name)
+        if err != nil {
+            return nil
+        }
+
+        probe := &wildcardAnswer{parent: parent, ips: make(map[string]bool)}
+        if len(hops) > 0 {
+            probe.target = strings.ToLower(hops[0].Target)
+        } else {
+            for _, ip := range lookupIPs(name) {
+                probe.ips[ip.String()] = true
+            }
+            if len(probe.ips) == 0 {
+                return nil
+            }
+        }
+
+        if answer == nil {
+            answer = probe
+            continue
+        }
+        if answer.target != probe.target {
+            return nil
+        }
+    }
+
+    return answer
+}
+
+func inheritsWildcard(result *Result, answer *wildcardAnswer) bool {
+    if answer.target != "" {
+        return len(result.CNAMEChain) > 0 && strings.EqualFold(result.CNAMEChain[0].Target, answer.target)
+    }
+    return len(result.CNAMEChain) == 0 && answer.ips[result.IP]
+}
+
+func randomLabel() string {
+    b := make([]byte, 8)
+    rand.Read(b)
+    return "subtake-" + hex.EncodeToString(b)
+}
+
+func httpHost(subdomain string) string {
+    if strings.HasPrefix(subdomain, "*.") {
+        return randomLabel() + subdomain[1:]
+    }
+    return subdomain
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/miekg/dns"
+)
+
+type zoneData struct {
+    apex   string
+    zoneID string
+    owners []string
+    names  []string
+    seen   map[string]bool
+    exist  map[string]bool
+    cnames map[string]CNAMEHop
+    ips    map[string][]net.IP
+    ns     map[string][]string
+    mx     map[string][]string
+}
+
+func newZoneData(apex string) *zoneData {
+    return &zoneData{
+        apex:   strings.ToLower(apex),
+        seen:   make(map[string]bool),
+        exist:  make(map[string]bool),
+        cnames: make(map[string]CNAMEHop),
+        ips:    make(map[string][]net.IP),
+        ns:     make(map[string][]string),
+        mx:     make(map[string][]string),
+    }
+}
+
+func (z *zoneData) add(rr dns.RR) {
+    owner := strings.ToLower(rr.Header().Name)
+    if !z.exist[owner] {
+        z.exist[owner] = true
+        z.names = append(z.names, owner)
+    }
+
+    switch record := rr.(type) {
+    case *dns.SOA:
+        if z.apex == "" {
+            z.apex = owner
+        }
+        return
+    case *dns.CNAME:
+        z.cnames[owner] = CNAMEHop{Name: owner, Target: strings.ToLower(record.Target), TTL: record.Hdr.Ttl}
+    case *dns.A:
+        z.ips[owner] = append(z.ips[owner], record.A)
+    case *dns.AAAA:
+        z.ips[owner] = append(z.ips[owner], record.AAAA)
+    case *dns.NS:
+        z.ns[owner] = append(z.ns[owner], strings.ToLower(record.Ns))
+    case *dns.MX:
+        z.mx[owner] = append(z.mx[owner], strings.ToLower(record.Mx))
+    default:
+        return
+    }
+
+    if !z.seen[owner] {
+        z.seen[owner] = true
+        z.owners = append(z.owners, owner)
+    }
+}
+
+func (z *zoneData) inZone(name string) bool {
+    return z.apex != "" && dns.IsSubDomain(z.apex, name)
+}
+
+func (z *zoneData) exists(name string) bool {
+    if z.exist[name] {
+        return true
+    }
+
+    labels := dns.SplitDomainName(name)
+    for i := 1; i < len(labels); i++ {
+        if z.exist["*."+dns.Fqdn(strings.Join(labels[i:], "."))] {
+            return true
+        }
+    }
+
+    for _, owner := range z.names {
+        if strings.HasSuffix(owner, "."+name) {
+            return true
+        }
+    }
+    return false
+}
+
+func (z *zoneData) targets() []scanTarget {
+    var targets []scanTarget
+
+    for _, owner := range z.owners {
+        answer := &resolvedAnswer{
+            hops:  followCNAMEs(owner, z.cnames),
+            rcode: rcodeUnknown,
+            mx:    z.mx[owner],
+        }
+        if owner != z.apex {
+            answer.nameServers = z.ns[owner]
+        }
+
+        final := owner
+        if len(answer.hops) > 0 {
+            final = answer.hops[len(answer.hops)-1].Target
+        }
+
+        switch {
+        case len(z.ips[final]) > 0:
+            answer.ips = z.ips[final]
+            answer.rcode = dns.RcodeSuccess
+        case z.inZone(final) && !z.delegated(final):
+            answer.rcode = dns.RcodeSuccess
+            if !z.exists(final) {
+                answer.rcode = dns.RcodeNameError
+            }
+        case len(answer.hops) == 0:
+            answer.rcode = dns.RcodeSuccess
+        }
+
+        targets = append(targets, scanTarget{name: strings.TrimSuffix(owner, "."), answer: answer, zoneID: z.zoneID})
+    }
+
+    return targets
+}
+
+func (z *zoneData) delegated(name string) bool {
+    for owner := range z.ns {
+        if owner != z.apex && dns.IsSubDomain(owner, name) {
+            return true
+        }
+    }
+    return false
+}
+
+func parseZoneFile(filename, origin string) (*zoneData, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, fmt.Errorf("opening zone file: %v", err)
+    }
+    defer file.Close()
+
+    return parseZoneData(file, origin, filename)
+}
+
+func parseZoneData(r io.Reader, origin, filename string) (*zoneData, error) {
+    if origin != "" {
+        origin = dns.Fqdn(origin)
+    }
+
+    zone := newZoneData(origin)
+    parser := dns.NewZoneParser(r, origin, filepath.Clean(filename))
+    parser.SetIncludeAllowed(true)
+
+    for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+        zone.add(rr)
+    }
+    if err := parser.Err(); err != nil {
+        return nil, fmt.Errorf("parsing zone file: %v", err)
+    }
+    if zone.apex == "" {
+        return nil, fmt.Errorf("zone file has no SOA record, set the origin with -zone-origin")
+    }
+
+    return zone, nil
+}
+
+func transferZone(spec string) (*zoneData, error) {
+    parts := strings.SplitN(spec, "@", 2)
+    if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+        return nil, fmt.Errorf("invalid -axfr value %q (expected zone@nameserver)", spec)
+    }
+    name, server := dns.Fqdn(parts[0]), parts[1]
+
+    addr, err := nameServerAddr(server)
+    if err != nil {
+        return nil, err
+    }
+
+    msg := new(dns.Msg)
+    msg.SetAxfr(name)
+
+    transfer := new(dns.Transfer)
+    envelopes, err := transfer.In(msg, addr)
+    if err != nil {
+        return nil, fmt.Errorf("AXFR %s from %s: %v", name, addr, err)
+    }
+
+    zone := newZoneData(name)
+    for envelope := range envelopes {
+        if envelope.Error != nil {
+            return nil, fmt.Errorf("AXFR %s from %s: %v", name, addr, envelope.Error)
+        }
+        for _, rr := range envelope.RR {
+            zone.add(rr)
+        }
+    }
+
+    return zone, nil
+}
+
+func nameServerAddr(server string) (string, error) {
+    if normalized, err := normalizeResolver(server); err == nil {
+        return normalized, nil
+    }
+
+    host, port := server, "53"
+    if h, p, err := net.SplitHostPort(server); err == nil {
+        host, port = h, p
+    }
+
+    ips := lookupIPs(host)
+    if len(ips) == 0 {
+        return "", fmt.Errorf("name server %s does not resolve", host)
+    }
+    return net.JoinHostPort(ips[0].String(), port), nil
+}
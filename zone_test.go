@@ -0,0 +1,124 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/miekg/dns"
+)
+
+const testZone = `$ORIGIN example.com.
+$TTL 300
+@           IN SOA  ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 300
+@           IN NS   ns1.example.com.
+@           IN MX   10 mail.example.com.
+ns1         IN A    192.0.2.53
+www         IN CNAME app.herokuapp.com.
+blog        IN CNAME www
+old         IN CNAME retired
+spf         IN CNAME _spf
+_spf        IN TXT  "v=spf1 -all"
+deep        IN CNAME nested
+a.nested    IN A    192.0.2.10
+child       IN NS   ns.dangling-dns.net.
+legacy      IN CNAME host.child
+$INCLUDE %s
+`
+
+const testIncludedZone = `static      IN A    192.0.2.80
+static      IN AAAA 2001:db8::80
+shop        IN CNAME shops.myshopify.com.
+`
+
+func TestParseZoneFileWithInclude(t *testing.T) {
+    dir := t.TempDir()
+    included := filepath.Join(dir, "included.zone")
+    if err := os.WriteFile(included, []byte(testIncludedZone), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    main := filepath.Join(dir, "example.com.zone")
+    if err := os.WriteFile(main, []byte(strings.Replace(testZone, "%s", included, 1)), 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    zone, err := parseZoneFile(main, "")
+    if err != nil {
+        t.Fatalf("parseZoneFile: %v", err)
+    }
+    if zone.apex != "example.com." {
+        t.Errorf("apex = %q, want example.com. from the SOA", zone.apex)
+    }
+
+    got := make(map[string]scanTarget)
+    for _, target := range zone.targets() {
+        got[target.name] = target
+    }
+
+    tests := []struct {
+        name  string
+        rcode int
+        hops  string
+        ips   string
+        ns    string
+        mx    string
+    }{
+        {name: "example.com", rcode: dns.RcodeSuccess, mx: "mail.example.com."},
+        {name: "ns1.example.com", rcode: dns.RcodeSuccess, ips: "192.0.2.53"},
+        {name: "www.example.com", rcode: rcodeUnknown, hops: "www.example.com.>app.herokuapp.com."},
+        {name: "blog.example.com", rcode: rcodeUnknown, hops: "blog.example.com.>www.example.com.,www.example.com.>app.herokuapp.com."},
+        {name: "old.example.com", rcode: dns.RcodeNameError, hops: "old.example.com.>retired.example.com."},
+        {name: "spf.example.com", rcode: dns.RcodeSuccess, hops: "spf.example.com.>_spf.example.com."},
+        {name: "deep.example.com", rcode: dns.RcodeSuccess, hops: "deep.example.com.>nested.example.com."},
+        {name: "a.nested.example.com", rcode: dns.RcodeSuccess, ips: "192.0.2.10"},
+        {name: "child.example.com", rcode: dns.RcodeSuccess, ns: "ns.dangling-dns.net."},
+        {name: "legacy.example.com", rcode: rcodeUnknown, hops: "legacy.example.com.>host.child.example.com."},
+        {name: "static.example.com", rcode: dns.RcodeSuccess, ips: "192.0.2.80,2001:db8::80"},
+        {name: "shop.example.com", rcode: rcodeUnknown, hops: "shop.example.com.>shops.myshopify.com."},
+    }
+
+    if len(got) != len(tests) {
+        t.Errorf("got %d targets, want %d", len(got), len(tests))
+    }
+    for _, tt := range tests {
+        target, ok := got[tt.name]
+        if !ok {
+            t.Errorf("%s: not a scan target", tt.name)
+            continue
+        }
+        if target.answer.rcode != tt.rcode {
+            t.Errorf("%s: rcode %d, want %d", tt.name, target.answer.rcode, tt.rcode)
+        }
+        if hops := hopChain(target.answer); hops != tt.hops {
+            t.Errorf("%s: hops %q, want %q", tt.name, hops, tt.hops)
+        }
+        if ips := ipList(target.answer); ips != tt.ips {
+            t.Errorf("%s: ips %q, want %q", tt.name, ips, tt.ips)
+        }
+        if ns := strings.Join(target.answer.nameServers, ","); ns != tt.ns {
+            t.Errorf("%s: name servers %q, want %q", tt.name, ns, tt.ns)
+        }
+        if mx := strings.Join(target.answer.mx, ","); mx != tt.mx {
+            t.Errorf("%s: mx %q, want %q", tt.name, mx, tt.mx)
+        }
+    }
+
+    if _, ok := got["_spf.example.com"]; ok {
+        t.Error("TXT-only owner _spf.example.com became a scan target")
+    }
+}
+
+func TestParseZoneDataNeedsOrigin(t *testing.T) {
+    if _, err := parseZoneData(strings.NewReader("www IN CNAME app.herokuapp.com.\n"), "", "zone"); err == nil {
+        t.Error("zone without SOA or origin was accepted")
+    }
+
+    zone, err := parseZoneData(strings.NewReader("www IN CNAME app.herokuapp.com.\n"), "example.com", "zone")
+    if err != nil {
+        t.Fatalf("parseZoneData with origin: %v", err)
+    }
+    if targets := zone.targets(); len(targets) != 1 || targets[0].name != "www.example.com" {
+        t.Errorf("targets = %+v, want www.example.com", targets)
+    }
+}